@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/malikfajr/cats-social/exception"
+)
+
+const realm = "cats-social"
+
+var (
+	errNoToken        = errors.New("no bearer token")
+	errMalformedToken = errors.New("authorization header is not a bearer token")
+	errRevokedSession = errors.New("session is revoked")
+	errNoUserId       = errors.New("token has no user id")
+)
+
+// Required rejects requests without a valid access token.
+func Required(next http.Handler) http.Handler {
+	return authenticate(next, false)
+}
+
+// Optional lets anonymous requests through, a presented token must still be
+// valid.
+func Optional(next http.Handler) http.Handler {
+	return authenticate(next, true)
+}
+
+func authenticate(next http.Handler, optional bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, err := bearerToken(r.Header.Get("Authorization"))
+		if errors.Is(err, errNoToken) && optional {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			unauthorized(w, r, err)
+			return
+		}
+
+		claims, err := ParseToken(tokenString)
+		if err != nil {
+			unauthorized(w, r, err)
+			return
+		}
+
+		// issued before users had ids, a refresh gets one that has
+		if claims.UserId == "" {
+			unauthorized(w, r, errNoUserId)
+			return
+		}
+
+		// logged out, revoked by the user or rotated after a replay
+		active, err := SeeSession(r, claims.SessionId)
+		if err != nil {
+			exception.WriteError(w, r, err)
+			return
+		}
+		if !active {
+			unauthorized(w, r, errRevokedSession)
+			return
+		}
+
+		ctx := NewContext(r.Context(), Principal{
+			Id:        claims.UserId,
+			Email:     claims.Email,
+			Name:      claims.Name,
+			Role:      claims.Role,
+			SessionId: claims.SessionId,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// bearerToken extracts the token of an RFC 6750 "Bearer <token>" header,
+// the scheme is case insensitive.
+func bearerToken(header string) (string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", errNoToken
+	}
+
+	scheme, token, ok := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", errMalformedToken
+	}
+
+	return token, nil
+}
+
+// unauthorized writes a 401 with the challenge of RFC 6750, a request that
+// sent no token at all gets no error code.
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errNoToken) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", realm))
+		exception.WriteError(w, r, exception.NewUnauthorizedError("token_missing"))
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\"", realm))
+	exception.WriteError(w, r, exception.NewUnauthorizedError("token_invalid").Wrap(err))
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/models"
+)
+
+type Permission string
+
+const (
+	ReadUsers     Permission = "users:read"
+	ManageUsers   Permission = "users:manage"
+	ManageCats    Permission = "cats:manage"
+	ManageMatches Permission = "matches:manage"
+	ReadAudit     Permission = "audit:read"
+)
+
+// rolePermissions is the policy of every role, a role missing from it has no
+// permission at all.
+var rolePermissions = map[string][]Permission{
+	models.RoleModerator: {ReadUsers, ManageCats, ManageMatches},
+	models.RoleAdmin:     {ReadUsers, ManageUsers, ManageCats, ManageMatches, ReadAudit},
+}
+
+// Can reports whether the principal's role grants permission.
+func (p Principal) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[p.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Permit rejects requests whose principal lacks permission. It must be
+// wrapped by Required.
+func Permit(permission Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := FromContext(r.Context())
+			if !ok || !principal.Can(permission) {
+				exception.WriteError(w, r, exception.NewForbiddenError("forbidden"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
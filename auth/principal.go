@@ -0,0 +1,25 @@
+package auth
+
+import "context"
+
+// Principal is the authenticated user of a request.
+type Principal struct {
+	Id        string
+	Email     string
+	Name      string
+	Role      string
+	SessionId string
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal of an authenticated request, ok is false
+// for an anonymous one.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/keyring"
+	"github.com/malikfajr/cats-social/models"
+)
+
+// leeway is the clock skew tolerated between this service and the ones
+// verifying its tokens.
+const leeway = 30 * time.Second
+
+var keys *keyring.Keyring
+
+var store models.Store
+
+func Init(k *keyring.Keyring, s models.Store) {
+	keys = k
+	store = s
+}
+
+// IssueToken signs an access token of user bound to the session sessionId.
+func IssueToken(user models.User, sessionId string) (string, error) {
+	now := time.Now()
+	claims := config.CustomJWTClaim{
+		UserId:    user.Id,
+		Email:     user.Email,
+		Name:      user.Name,
+		Role:      user.Role,
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    config.Env.JWT_ISSUER,
+			Subject:   user.Id,
+			Audience:  jwt.ClaimStrings{config.Env.JWT_AUDIENCE},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(config.Env.ACCESS_TOKEN_TTL)),
+		},
+	}
+
+	return keys.Sign(claims)
+}
+
+// ParseToken verifies the signature, exp, iat, nbf, iss and aud of an access
+// token and returns its claims.
+func ParseToken(tokenString string) (*config.CustomJWTClaim, error) {
+	claims := &config.CustomJWTClaim{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc,
+		jwt.WithValidMethods(keys.Methods()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(config.Env.JWT_ISSUER),
+		jwt.WithAudience(config.Env.JWT_AUDIENCE),
+		jwt.WithLeeway(leeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// touchInterval is how stale the last seen time of a session may get
+// before a request updates it, sparing a write on every request.
+const touchInterval = time.Minute
+
+// SeeSession reports whether the session an access token is bound to has
+// not been revoked, and records r as its last activity.
+func SeeSession(r *http.Request, sessionId string) (bool, error) {
+	var active bool
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		session, err := tx.Sessions().GetSession(r.Context(), sessionId)
+		if errors.Is(err, models.ErrSessionNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		active = session.RevokedAt == nil
+		if !active || time.Since(session.LastSeenAt) < touchInterval {
+			return nil
+		}
+
+		return tx.Sessions().TouchSession(r.Context(), session.Id, helper.ClientIP(r), helper.UserAgent(r))
+	})
+
+	return active, err
+}
+
+// JWKSHandler publishes the public keys verifying access tokens.
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helper.WriteToResponseBody(w, keys.JWKS(), http.StatusOK)
+}
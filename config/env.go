@@ -4,17 +4,64 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	BCRYPT_SALT int
-	db_port     int
-	db_name     string
-	db_host     string
-	db_username string
-	db_password string
-	db_params   string
-	JWT_SECRET  string
+	PASSWORD_HASHER      string
+	BCRYPT_COST          int
+	ARGON2_MEMORY        int
+	ARGON2_ITERATIONS    int
+	ARGON2_PARALLELISM   int
+	PASSWORD_MIN_LENGTH  int
+	PASSWORD_MAX_LENGTH  int
+	PASSWORD_MIN_SCORE   int
+	db_port              int
+	db_name              string
+	db_host              string
+	db_username          string
+	db_password          string
+	db_params            string
+	JWT_SECRET           string
+	JWT_KEYS             string
+	JWT_SIGNING_KEY      string
+	JWT_ISSUER           string
+	JWT_AUDIENCE         string
+	STORAGE              string
+	REQUEST_TIMEOUT      time.Duration
+	DB_STATEMENT_TIMEOUT time.Duration
+	route_timeouts       map[string]time.Duration
+	MAX_BODY_SIZE        int64
+	ACCESS_TOKEN_TTL     time.Duration
+	REFRESH_TOKEN_TTL    time.Duration
+	APP_URL              string
+	PASSWORD_RESET_TTL   time.Duration
+	API_URL              string
+	CAT_NUMERIC_IDS      bool
+
+	EMAIL_VERIFICATION_TTL             time.Duration
+	EMAIL_VERIFICATION_RESEND_INTERVAL time.Duration
+	email_verification_required        map[string]bool
+	MAILER                             string
+	MAIL_FROM                          string
+	MAIL_DIR                           string
+	SMTP_HOST                          string
+	SMTP_PORT                          int
+	SMTP_USERNAME                      string
+	SMTP_PASSWORD                      string
+	TRUSTED_PROXY_HOPS                 int
+	MFA_ISSUER                         string
+	MFA_CHALLENGE_TTL                  time.Duration
+
+	LOGIN_FREE_ATTEMPTS       int
+	LOGIN_LOCKOUT_ATTEMPTS    int
+	LOGIN_IP_FREE_ATTEMPTS    int
+	LOGIN_IP_LOCKOUT_ATTEMPTS int
+	LOGIN_BACKOFF_BASE        time.Duration
+	LOGIN_BACKOFF_MAX         time.Duration
+	LOGIN_LOCKOUT_DURATION    time.Duration
+	LOGIN_ATTEMPT_WINDOW      time.Duration
 }
 
 var Env Config
@@ -26,8 +73,52 @@ func InitEnv() {
 	Env.db_username = getEnv("DB_USERNAME", "postgres").(string)
 	Env.db_password = getEnv("DB_PASSWORD", "secret").(string)
 	Env.db_params = getEnv("DB_PARAMS", "sslmode=disable").(string)
-	Env.JWT_SECRET = getEnv("JWT_SECRET", "not-define").(string)
-	Env.BCRYPT_SALT = getEnv("BCRYPT_SALT", 8).(int)
+	Env.JWT_SECRET = getEnv("JWT_SECRET", "").(string)
+	Env.JWT_KEYS = getEnv("JWT_KEYS", "").(string)
+	Env.JWT_SIGNING_KEY = getEnv("JWT_SIGNING_KEY", "").(string)
+	Env.JWT_ISSUER = getEnv("JWT_ISSUER", "cats-social").(string)
+	Env.JWT_AUDIENCE = getEnv("JWT_AUDIENCE", "cats-social").(string)
+	Env.PASSWORD_HASHER = getEnv("PASSWORD_HASHER", "argon2id").(string)
+	// BCRYPT_SALT is the former, misnamed, BCRYPT_COST
+	Env.BCRYPT_COST = getEnv("BCRYPT_COST", getEnv("BCRYPT_SALT", 8)).(int)
+	Env.ARGON2_MEMORY = getEnv("ARGON2_MEMORY", 19*1024).(int)
+	Env.ARGON2_ITERATIONS = getEnv("ARGON2_ITERATIONS", 2).(int)
+	Env.ARGON2_PARALLELISM = getEnv("ARGON2_PARALLELISM", 1).(int)
+	Env.PASSWORD_MIN_LENGTH = getEnv("PASSWORD_MIN_LENGTH", 8).(int)
+	Env.PASSWORD_MAX_LENGTH = getEnv("PASSWORD_MAX_LENGTH", 128).(int)
+	Env.PASSWORD_MIN_SCORE = getEnv("PASSWORD_MIN_SCORE", 2).(int)
+	Env.STORAGE = getEnv("STORAGE", "postgres").(string)
+	Env.REQUEST_TIMEOUT = getEnv("REQUEST_TIMEOUT", 10*time.Second).(time.Duration)
+	Env.DB_STATEMENT_TIMEOUT = getEnv("DB_STATEMENT_TIMEOUT", 5*time.Second).(time.Duration)
+	Env.route_timeouts = parseRouteTimeouts(getEnv("ROUTE_TIMEOUTS", "").(string))
+	Env.MAX_BODY_SIZE = int64(getEnv("MAX_BODY_SIZE", 1<<20).(int))
+	Env.ACCESS_TOKEN_TTL = getEnv("ACCESS_TOKEN_TTL", 15*time.Minute).(time.Duration)
+	Env.REFRESH_TOKEN_TTL = getEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour).(time.Duration)
+	Env.APP_URL = strings.TrimSuffix(getEnv("APP_URL", "http://localhost:8080").(string), "/")
+	Env.PASSWORD_RESET_TTL = getEnv("PASSWORD_RESET_TTL", time.Hour).(time.Duration)
+	Env.API_URL = strings.TrimSuffix(getEnv("API_URL", "http://localhost:8080").(string), "/")
+	Env.CAT_NUMERIC_IDS = getEnv("CAT_NUMERIC_IDS", false).(bool)
+	Env.EMAIL_VERIFICATION_TTL = getEnv("EMAIL_VERIFICATION_TTL", 24*time.Hour).(time.Duration)
+	Env.EMAIL_VERIFICATION_RESEND_INTERVAL = getEnv("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute).(time.Duration)
+	Env.email_verification_required = parseList(getEnv("EMAIL_VERIFICATION_REQUIRED", "match").(string))
+	Env.MAILER = getEnv("MAILER", "stdout").(string)
+	Env.MAIL_FROM = getEnv("MAIL_FROM", "Cats Social <no-reply@localhost>").(string)
+	Env.MAIL_DIR = getEnv("MAIL_DIR", "mail").(string)
+	Env.SMTP_HOST = getEnv("SMTP_HOST", "localhost").(string)
+	Env.SMTP_PORT = getEnv("SMTP_PORT", 587).(int)
+	Env.SMTP_USERNAME = getEnv("SMTP_USERNAME", "").(string)
+	Env.SMTP_PASSWORD = getEnv("SMTP_PASSWORD", "").(string)
+	Env.TRUSTED_PROXY_HOPS = getEnv("TRUSTED_PROXY_HOPS", 0).(int)
+	Env.MFA_ISSUER = getEnv("MFA_ISSUER", "Cats Social").(string)
+	Env.MFA_CHALLENGE_TTL = getEnv("MFA_CHALLENGE_TTL", 5*time.Minute).(time.Duration)
+	Env.LOGIN_FREE_ATTEMPTS = getEnv("LOGIN_FREE_ATTEMPTS", 3).(int)
+	Env.LOGIN_LOCKOUT_ATTEMPTS = getEnv("LOGIN_LOCKOUT_ATTEMPTS", 10).(int)
+	Env.LOGIN_IP_FREE_ATTEMPTS = getEnv("LOGIN_IP_FREE_ATTEMPTS", 20).(int)
+	Env.LOGIN_IP_LOCKOUT_ATTEMPTS = getEnv("LOGIN_IP_LOCKOUT_ATTEMPTS", 100).(int)
+	Env.LOGIN_BACKOFF_BASE = getEnv("LOGIN_BACKOFF_BASE", time.Second).(time.Duration)
+	Env.LOGIN_BACKOFF_MAX = getEnv("LOGIN_BACKOFF_MAX", 5*time.Minute).(time.Duration)
+	Env.LOGIN_LOCKOUT_DURATION = getEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute).(time.Duration)
+	Env.LOGIN_ATTEMPT_WINDOW = getEnv("LOGIN_ATTEMPT_WINDOW", 15*time.Minute).(time.Duration)
 }
 
 func getEnv(key string, defaultValue interface{}) interface{} {
@@ -45,6 +136,18 @@ func getEnv(key string, defaultValue interface{}) interface{} {
 			return defaultValue
 		}
 		return intValue
+	case bool:
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return defaultValue
+		}
+		return boolValue
+	case time.Duration:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return defaultValue
+		}
+		return duration
 	default:
 		return defaultValue
 	}
@@ -54,3 +157,54 @@ func GetDbAddress() string {
 	address := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?%s", Env.db_username, Env.db_password, Env.db_host, Env.db_port, Env.db_name, Env.db_params)
 	return address
 }
+
+// parseRouteTimeouts reads a comma separated list of pattern=duration pairs,
+// e.g. "GET /v1/cat=3s,POST /v1/cat/match=2s".
+func parseRouteTimeouts(value string) map[string]time.Duration {
+	timeouts := map[string]time.Duration{}
+
+	for _, pair := range strings.Split(value, ",") {
+		pattern, durationStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+
+		duration, err := time.ParseDuration(strings.TrimSpace(durationStr))
+		if err != nil {
+			continue
+		}
+
+		timeouts[strings.TrimSpace(pattern)] = duration
+	}
+
+	return timeouts
+}
+
+// RouteTimeout returns the request deadline of a route pattern, falling back
+// to REQUEST_TIMEOUT.
+func RouteTimeout(pattern string) time.Duration {
+	if timeout, ok := Env.route_timeouts[pattern]; ok {
+		return timeout
+	}
+
+	return Env.REQUEST_TIMEOUT
+}
+
+// parseList reads a comma separated list of names, e.g. "match,cat".
+func parseList(value string) map[string]bool {
+	names := map[string]bool{}
+
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+
+	return names
+}
+
+// EmailVerificationRequired reports whether action, "match" or "cat", is
+// blocked until the user verified their email.
+func EmailVerificationRequired(action string) bool {
+	return Env.email_verification_required[action]
+}
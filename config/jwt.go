@@ -2,9 +2,14 @@ package config
 
 import "github.com/golang-jwt/jwt/v5"
 
+// CustomJWTClaim are the claims of an access token. UserId is the id of the
+// user, also its subject, since the email can change. SessionId is the
+// refresh token family the access token was issued from.
 type CustomJWTClaim struct {
-	Email string           `json:"email"`
-	Name  string           `json:"name"`
-	Exp   *jwt.NumericDate `json:"exp"`
+	UserId    string `json:"uid"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	SessionId string `json:"sid"`
 	jwt.RegisteredClaims
 }
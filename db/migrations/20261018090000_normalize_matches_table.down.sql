@@ -0,0 +1,31 @@
+ALTER TABLE matches
+    ADD COLUMN issued_by JSONB,
+    ADD COLUMN match_cat_detail JSONB,
+    ADD COLUMN user_cat_detail JSONB;
+
+UPDATE matches m SET
+    issued_by = jsonb_build_object('name', u.name, 'email', u.email, 'createdAt', m.created_at),
+    match_cat_detail = jsonb_build_object(
+        'id', rc.id::TEXT, 'name', rc.name, 'race', rc.race, 'sex', rc.sex, 'description', rc.description,
+        'ageInMonth', rc.age_in_month, 'imageUrls', rc.image_urls, 'hasMatched', rc.hasmatched, 'createdAt', rc.created_at),
+    user_cat_detail = jsonb_build_object(
+        'id', ic.id::TEXT, 'name', ic.name, 'race', ic.race, 'sex', ic.sex, 'description', ic.description,
+        'ageInMonth', ic.age_in_month, 'imageUrls', ic.image_urls, 'hasMatched', ic.hasmatched, 'createdAt', ic.created_at)
+FROM users u, cats rc, cats ic
+WHERE u.email = m.issuer_email AND rc.id = m.receiver_cat_id AND ic.id = m.issuer_cat_id;
+
+ALTER TABLE matches
+    ALTER COLUMN issued_by SET NOT NULL,
+    ALTER COLUMN match_cat_detail SET NOT NULL,
+    ALTER COLUMN user_cat_detail SET NOT NULL;
+
+ALTER TABLE matches DROP CONSTRAINT IF EXISTS matches_match_user_email_fkey;
+
+DROP INDEX IF EXISTS idx_match_user_email;
+
+ALTER TABLE matches
+    DROP COLUMN issuer_email,
+    DROP COLUMN issuer_cat_id,
+    DROP COLUMN receiver_cat_id;
+
+CREATE INDEX IF NOT EXISTS idx_match_issued_by ON matches(issued_by);
@@ -0,0 +1,41 @@
+ALTER TABLE matches
+    ADD COLUMN issuer_email VARCHAR(50),
+    ADD COLUMN issuer_cat_id BIGINT,
+    ADD COLUMN receiver_cat_id BIGINT;
+
+UPDATE matches SET
+    issuer_email = issued_by->>'email',
+    issuer_cat_id = (user_cat_detail->>'id')::BIGINT,
+    receiver_cat_id = (match_cat_detail->>'id')::BIGINT;
+
+-- matches of cats or users deleted before this migration cannot reference them anymore
+DELETE FROM matches
+WHERE issuer_cat_id NOT IN (SELECT id FROM cats)
+    OR receiver_cat_id NOT IN (SELECT id FROM cats)
+    OR issuer_email NOT IN (SELECT email FROM users)
+    OR match_user_email NOT IN (SELECT email FROM users);
+
+ALTER TABLE matches
+    ALTER COLUMN issuer_email SET NOT NULL,
+    ALTER COLUMN issuer_cat_id SET NOT NULL,
+    ALTER COLUMN receiver_cat_id SET NOT NULL;
+
+ALTER TABLE matches ADD FOREIGN KEY (issuer_email) REFERENCES users (email);
+ALTER TABLE matches ADD FOREIGN KEY (match_user_email) REFERENCES users (email);
+ALTER TABLE matches ADD FOREIGN KEY (issuer_cat_id) REFERENCES cats (id) ON DELETE CASCADE;
+ALTER TABLE matches ADD FOREIGN KEY (receiver_cat_id) REFERENCES cats (id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_match_issued_by;
+
+ALTER TABLE matches
+    DROP COLUMN issued_by,
+    DROP COLUMN match_cat_detail,
+    DROP COLUMN user_cat_detail;
+
+CREATE INDEX IF NOT EXISTS idx_match_issuer_email ON matches(issuer_email);
+
+CREATE INDEX IF NOT EXISTS idx_match_user_email ON matches(match_user_email);
+
+CREATE INDEX IF NOT EXISTS idx_match_issuer_cat_id ON matches(issuer_cat_id);
+
+CREATE INDEX IF NOT EXISTS idx_match_receiver_cat_id ON matches(receiver_cat_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS audit_logs;
+
+DROP INDEX IF EXISTS idx_user_role;
+
+ALTER TABLE users
+    DROP COLUMN role,
+    DROP COLUMN disabled_at;
+
+DROP TYPE IF EXISTS USER_ROLE;
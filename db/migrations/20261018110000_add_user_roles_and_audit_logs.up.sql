@@ -0,0 +1,21 @@
+CREATE TYPE USER_ROLE AS ENUM ('user', 'moderator', 'admin');
+
+ALTER TABLE users
+    ADD COLUMN role USER_ROLE NOT NULL DEFAULT 'user',
+    ADD COLUMN disabled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_user_role ON users(role);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    actor_email VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(20) NOT NULL,
+    target_id VARCHAR(100) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_logs(created_at);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_email ON audit_logs(actor_email);
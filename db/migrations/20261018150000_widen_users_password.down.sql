@@ -0,0 +1,2 @@
+-- fails while argon2id hashes are stored, log every user in with PASSWORD_HASHER=bcrypt first
+ALTER TABLE users ALTER COLUMN password TYPE CHAR(60);
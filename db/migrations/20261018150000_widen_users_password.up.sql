@@ -0,0 +1,2 @@
+-- room for PHC strings of any algorithm, e.g. argon2id, not only bcrypt
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(255);
@@ -0,0 +1,9 @@
+ALTER TABLE refresh_tokens
+    DROP CONSTRAINT IF EXISTS fk_refresh_token_session,
+    ADD COLUMN revoked_at TIMESTAMPTZ;
+
+UPDATE refresh_tokens SET revoked_at = sessions.revoked_at
+FROM sessions
+WHERE sessions.id = refresh_tokens.family_id;
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_email VARCHAR(50) NOT NULL REFERENCES users(email) ON DELETE CASCADE,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_user_email ON sessions(user_email);
+
+-- every refresh token family becomes a session, revoked when all its tokens were
+INSERT INTO sessions (id, user_email, created_at, last_seen_at, revoked_at)
+SELECT family_id, MIN(user_email), MIN(created_at), MAX(created_at),
+       CASE WHEN BOOL_AND(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+FROM refresh_tokens
+GROUP BY family_id;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT fk_refresh_token_session FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE,
+    DROP COLUMN revoked_at;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_user_id;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS id,
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS avatar_url,
+    DROP COLUMN IF EXISTS city;
@@ -0,0 +1,8 @@
+ALTER TABLE users
+    ADD COLUMN id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN bio VARCHAR(160) NOT NULL DEFAULT '',
+    ADD COLUMN avatar_url VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN city VARCHAR(50) NOT NULL DEFAULT '';
+
+-- the public identifier of a user, the email stays private
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_id ON users(id);
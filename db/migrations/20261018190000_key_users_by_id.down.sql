@@ -0,0 +1,66 @@
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS email;
+
+-- pending email changes cannot be told apart once the column is gone
+DELETE FROM user_tokens WHERE purpose = 'email_change';
+
+ALTER TABLE cats ADD COLUMN user_email VARCHAR(50);
+UPDATE cats SET user_email = users.email FROM users WHERE users.id = cats.user_id;
+
+ALTER TABLE matches
+    ADD COLUMN issuer_email VARCHAR(50),
+    ADD COLUMN match_user_email VARCHAR(50);
+UPDATE matches SET issuer_email = users.email FROM users WHERE users.id = matches.issuer_id;
+UPDATE matches SET match_user_email = users.email FROM users WHERE users.id = matches.match_user_id;
+
+ALTER TABLE refresh_tokens ADD COLUMN user_email VARCHAR(50);
+UPDATE refresh_tokens SET user_email = users.email FROM users WHERE users.id = refresh_tokens.user_id;
+
+ALTER TABLE sessions ADD COLUMN user_email VARCHAR(50);
+UPDATE sessions SET user_email = users.email FROM users WHERE users.id = sessions.user_id;
+
+ALTER TABLE user_tokens ADD COLUMN user_email VARCHAR(50);
+UPDATE user_tokens SET user_email = users.email FROM users WHERE users.id = user_tokens.user_id;
+
+ALTER TABLE recovery_codes ADD COLUMN user_email VARCHAR(50);
+UPDATE recovery_codes SET user_email = users.email FROM users WHERE users.id = recovery_codes.user_id;
+
+ALTER TABLE cats DROP COLUMN user_id;
+ALTER TABLE matches DROP COLUMN issuer_id, DROP COLUMN match_user_id;
+ALTER TABLE refresh_tokens DROP COLUMN user_id;
+ALTER TABLE sessions DROP COLUMN user_id;
+ALTER TABLE user_tokens DROP COLUMN user_id;
+ALTER TABLE recovery_codes DROP COLUMN user_id;
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users ADD PRIMARY KEY (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_id ON users(id);
+
+ALTER TABLE cats
+    ALTER COLUMN user_email SET NOT NULL,
+    ADD FOREIGN KEY (user_email) REFERENCES users (email);
+ALTER TABLE matches
+    ALTER COLUMN issuer_email SET NOT NULL,
+    ALTER COLUMN match_user_email SET NOT NULL,
+    ADD FOREIGN KEY (issuer_email) REFERENCES users (email),
+    ADD FOREIGN KEY (match_user_email) REFERENCES users (email);
+ALTER TABLE refresh_tokens
+    ALTER COLUMN user_email SET NOT NULL,
+    ADD FOREIGN KEY (user_email) REFERENCES users (email) ON DELETE CASCADE;
+ALTER TABLE sessions
+    ALTER COLUMN user_email SET NOT NULL,
+    ADD FOREIGN KEY (user_email) REFERENCES users (email) ON DELETE CASCADE;
+ALTER TABLE user_tokens
+    ALTER COLUMN user_email SET NOT NULL,
+    ADD FOREIGN KEY (user_email) REFERENCES users (email) ON DELETE CASCADE;
+ALTER TABLE recovery_codes
+    ALTER COLUMN user_email SET NOT NULL,
+    ADD FOREIGN KEY (user_email) REFERENCES users (email) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS id_cat_email ON cats(user_email);
+CREATE INDEX IF NOT EXISTS idx_match_issuer_email ON matches(issuer_email);
+CREATE INDEX IF NOT EXISTS idx_match_user_email ON matches(match_user_email);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_user_email ON refresh_tokens(user_email);
+CREATE INDEX IF NOT EXISTS idx_session_user_email ON sessions(user_email);
+CREATE INDEX IF NOT EXISTS idx_user_token_user_email ON user_tokens(user_email, purpose);
+CREATE INDEX IF NOT EXISTS idx_recovery_code_user_email ON recovery_codes(user_email);
@@ -0,0 +1,68 @@
+-- every reference to a user moves from its email to its id, so the email
+-- can change and stays out of other tables
+ALTER TABLE cats ADD COLUMN user_id UUID;
+UPDATE cats SET user_id = users.id FROM users WHERE users.email = cats.user_email;
+
+ALTER TABLE matches
+    ADD COLUMN issuer_id UUID,
+    ADD COLUMN match_user_id UUID;
+UPDATE matches SET issuer_id = users.id FROM users WHERE users.email = matches.issuer_email;
+UPDATE matches SET match_user_id = users.id FROM users WHERE users.email = matches.match_user_email;
+
+ALTER TABLE refresh_tokens ADD COLUMN user_id UUID;
+UPDATE refresh_tokens SET user_id = users.id FROM users WHERE users.email = refresh_tokens.user_email;
+
+ALTER TABLE sessions ADD COLUMN user_id UUID;
+UPDATE sessions SET user_id = users.id FROM users WHERE users.email = sessions.user_email;
+
+ALTER TABLE user_tokens ADD COLUMN user_id UUID;
+UPDATE user_tokens SET user_id = users.id FROM users WHERE users.email = user_tokens.user_email;
+
+-- the address a token was sent to, the one of its user for the tokens sent
+-- so far, or the new address of an email change
+ALTER TABLE user_tokens ADD COLUMN email VARCHAR(50) NOT NULL DEFAULT '';
+UPDATE user_tokens SET email = user_tokens.user_email;
+
+ALTER TABLE recovery_codes ADD COLUMN user_id UUID;
+UPDATE recovery_codes SET user_id = users.id FROM users WHERE users.email = recovery_codes.user_email;
+
+-- dropping the email columns drops their foreign keys and indexes too
+ALTER TABLE cats DROP COLUMN user_email;
+ALTER TABLE matches DROP COLUMN issuer_email, DROP COLUMN match_user_email;
+ALTER TABLE refresh_tokens DROP COLUMN user_email;
+ALTER TABLE sessions DROP COLUMN user_email;
+ALTER TABLE user_tokens DROP COLUMN user_email;
+ALTER TABLE recovery_codes DROP COLUMN user_email;
+
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users ADD CONSTRAINT users_pkey PRIMARY KEY USING INDEX idx_user_id;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE cats
+    ALTER COLUMN user_id SET NOT NULL,
+    ADD FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE matches
+    ALTER COLUMN issuer_id SET NOT NULL,
+    ALTER COLUMN match_user_id SET NOT NULL,
+    ADD FOREIGN KEY (issuer_id) REFERENCES users (id),
+    ADD FOREIGN KEY (match_user_id) REFERENCES users (id);
+ALTER TABLE refresh_tokens
+    ALTER COLUMN user_id SET NOT NULL,
+    ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE sessions
+    ALTER COLUMN user_id SET NOT NULL,
+    ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE user_tokens
+    ALTER COLUMN user_id SET NOT NULL,
+    ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE recovery_codes
+    ALTER COLUMN user_id SET NOT NULL,
+    ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_cat_user_id ON cats(user_id);
+CREATE INDEX IF NOT EXISTS idx_match_issuer_id ON matches(issuer_id);
+CREATE INDEX IF NOT EXISTS idx_match_user_id ON matches(match_user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_user_id ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_session_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_token_user_id ON user_tokens(user_id, purpose);
+CREATE INDEX IF NOT EXISTS idx_recovery_code_user_id ON recovery_codes(user_id);
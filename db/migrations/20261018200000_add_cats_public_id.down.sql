@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_cat_public_id;
+
+ALTER TABLE cats DROP COLUMN public_id;
@@ -0,0 +1,33 @@
+-- ULID of an existing cat from its creation time: 10 base32 characters of
+-- milliseconds since the epoch then 16 random ones, one per byte of a random
+-- UUID (the version byte only brings 4 random bits)
+CREATE FUNCTION cat_ulid(ts TIMESTAMP) RETURNS TEXT AS $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    ms BIGINT := FLOOR(EXTRACT(EPOCH FROM ts) * 1000);
+    noise BYTEA := uuid_send(gen_random_uuid());
+    result TEXT := '';
+BEGIN
+    FOR i IN 1..10 LOOP
+        result := SUBSTR(alphabet, (ms % 32)::INT + 1, 1) || result;
+        ms := ms / 32;
+    END LOOP;
+
+    FOR i IN 0..15 LOOP
+        result := result || SUBSTR(alphabet, GET_BYTE(noise, i) % 32 + 1, 1);
+    END LOOP;
+
+    RETURN result;
+END
+$$ LANGUAGE plpgsql VOLATILE;
+
+-- the public identifier of a cat, the sequential id stays internal
+ALTER TABLE cats ADD COLUMN public_id VARCHAR(26);
+
+UPDATE cats SET public_id = cat_ulid(COALESCE(created_at, NOW()::TIMESTAMP));
+
+ALTER TABLE cats ALTER COLUMN public_id SET NOT NULL;
+
+DROP FUNCTION cat_ulid(TIMESTAMP);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cat_public_id ON cats(public_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_cat_created_at_public_id;
+
+CREATE INDEX IF NOT EXISTS idx_cat_created_at ON cats(created_at);
+
+ALTER TABLE cats ALTER COLUMN created_at DROP NOT NULL;
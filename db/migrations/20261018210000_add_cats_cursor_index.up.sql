@@ -0,0 +1,8 @@
+-- keyset pagination compares (created_at, public_id), which needs both set
+UPDATE cats SET created_at = NOW() WHERE created_at IS NULL;
+
+ALTER TABLE cats ALTER COLUMN created_at SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_cat_created_at;
+
+CREATE INDEX IF NOT EXISTS idx_cat_created_at_public_id ON cats(created_at, public_id);
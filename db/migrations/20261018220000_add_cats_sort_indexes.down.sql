@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_cat_name_trgm;
+
+DROP INDEX IF EXISTS idx_cat_name_public_id;
+
+DROP INDEX IF EXISTS idx_cat_age_in_month_public_id;
+
+CREATE INDEX IF NOT EXISTS idx_cat_age ON cats(age_in_month);
@@ -0,0 +1,9 @@
+-- every sort ends with the public id, see GET /v1/cat?sort=
+DROP INDEX IF EXISTS idx_cat_age;
+
+CREATE INDEX IF NOT EXISTS idx_cat_age_in_month_public_id ON cats(age_in_month, public_id);
+
+CREATE INDEX IF NOT EXISTS idx_cat_name_public_id ON cats(name, public_id);
+
+-- the search filter, LOWER(name) LIKE '%...%', ahead of sorting by relevance
+CREATE INDEX IF NOT EXISTS idx_cat_name_trgm ON cats USING GIN (LOWER(name) gin_trgm_ops);
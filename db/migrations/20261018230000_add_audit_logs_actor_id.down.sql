@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audit_log_actor_id;
+
+UPDATE audit_logs a SET target_id = u.email FROM users u WHERE a.target_type = 'user' AND u.id::TEXT = a.target_id;
+
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS actor_id;
@@ -0,0 +1,12 @@
+-- actors and target users are kept by id, their email only as of the entry
+ALTER TABLE audit_logs ADD COLUMN actor_id UUID;
+
+UPDATE audit_logs a SET actor_id = u.id FROM users u WHERE u.email = a.actor_email;
+
+UPDATE audit_logs a
+SET target_id = u.id::TEXT,
+    detail = CASE WHEN a.detail = '' THEN 'email ' || a.target_id ELSE 'email ' || a.target_id || ', ' || a.detail END
+FROM users u
+WHERE a.target_type = 'user' AND u.email = a.target_id;
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_logs(actor_id);
@@ -0,0 +1,12 @@
+-- the matches and cats of deleted accounts cannot be kept without an owner
+DELETE FROM matches WHERE issuer_id IS NULL OR match_user_id IS NULL;
+
+DELETE FROM cats WHERE user_id IS NULL;
+
+ALTER TABLE matches
+    ALTER COLUMN issuer_id SET NOT NULL,
+    ALTER COLUMN match_user_id SET NOT NULL;
+
+ALTER TABLE cats ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS private;
@@ -0,0 +1,10 @@
+-- private cats are listed to their owner only
+ALTER TABLE cats ADD COLUMN private BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- a deleted account leaves its cats with an approved match behind, without
+-- an owner, so the other side of the match keeps it
+ALTER TABLE cats ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE matches
+    ALTER COLUMN issuer_id DROP NOT NULL,
+    ALTER COLUMN match_user_id DROP NOT NULL;
@@ -0,0 +1,2 @@
+UPDATE audit_logs SET action = 'mfa_enabled' WHERE action = 'user.mfa_enable';
+UPDATE audit_logs SET action = 'mfa_disabled' WHERE action = 'user.mfa_disable';
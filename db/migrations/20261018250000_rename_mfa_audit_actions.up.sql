@@ -0,0 +1,3 @@
+-- the MFA actions follow the <target>.<verb> style of the other actions
+UPDATE audit_logs SET action = 'user.mfa_enable' WHERE action = 'mfa_enabled';
+UPDATE audit_logs SET action = 'user.mfa_disable' WHERE action = 'mfa_disabled';
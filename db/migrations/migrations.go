@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migration files so the binary can
+// migrate the database without the external migrate tool.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -1,9 +0,0 @@
-package exception
-
-type ConflictError struct {
-	Error string
-}
-
-func NewConflictError(error string) ConflictError {
-	return ConflictError{Error: error}
-}
@@ -1,107 +1,123 @@
 package exception
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"runtime/debug"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
 	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/i18n"
+	"github.com/malikfajr/cats-social/models"
 )
 
+// StatusClientClosedRequest is the nginx convention for a request the client
+// gave up on before the response was written.
+const StatusClientClosedRequest = 499
+
+// knownErrors are the responses of model and request errors a handler did
+// not translate itself.
+var knownErrors = []struct {
+	err    error
+	status int
+	key    string
+}{
+	{models.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{models.ErrCatNotFound, http.StatusNotFound, "cat_not_found"},
+	{models.ErrMatchNotFound, http.StatusNotFound, "match_not_found"},
+	{models.ErrSessionNotFound, http.StatusNotFound, "session_not_found"},
+	{models.ErrEmailTaken, http.StatusConflict, "email_taken"},
+	{models.ErrMatchNotPending, http.StatusBadRequest, "match_not_pending"},
+	{models.ErrInvalidParam, http.StatusBadRequest, "invalid_param"},
+	{helper.ErrInvalidBody, http.StatusBadRequest, "invalid_body"},
+	{helper.ErrBodyTooLarge, http.StatusRequestEntityTooLarge, "request_body_too_large"},
+	{helper.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+}
+
+// RecoverWrap turns a panicking handler into an internal server error.
 func RecoverWrap(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
-			if err := recover(); err != nil {
-				if validationError(w, r, err) {
-					return
-				}
-
-				if badRequestError(w, r, err) {
-					return
-				}
-
-				if notFoundError(w, r, err) {
-					return
+			if p := recover(); p != nil {
+				err, ok := p.(error)
+				if !ok {
+					err = fmt.Errorf("%v", p)
 				}
 
-				if confilctError(w, r, err) {
-					return
-				}
-
-				internalServerError(w, r, err)
+				log.Printf("panic: %v\n%s", p, debug.Stack())
+				WriteError(w, r, err)
 			}
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
 
-func confilctError(writer http.ResponseWriter, request *http.Request, err interface{}) bool {
-	exception, ok := err.(ConflictError)
-	if ok {
-		wrapper := helper.WebResponse{
-			Message: exception.Error,
-			Data:    nil,
-		}
-		helper.WriteToResponseBody(writer, wrapper, http.StatusConflict)
-		return true
-	} else {
-		return false
+// WriteError writes the response matching err in the locale of the request.
+// Server errors and errors wrapping a cause are logged with the whole chain.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	locale := i18n.FromRequest(r)
+	status, message := statusOf(r, locale, err)
+
+	if status >= http.StatusInternalServerError || errors.Unwrap(err) != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.String(), err)
 	}
-}
 
-func notFoundError(writer http.ResponseWriter, request *http.Request, err interface{}) bool {
-	exception, ok := err.(NotFoundError)
-	if ok {
-		wrapper := helper.WebResponse{
-			Message: exception.Error,
-			Data:    nil,
-		}
-		helper.WriteToResponseBody(writer, wrapper, http.StatusNotFound)
-		return true
-	} else {
-		return false
+	webResponse := helper.WebResponse{
+		Message: message,
+		Data:    nil,
 	}
-}
 
-func badRequestError(writer http.ResponseWriter, request *http.Request, err interface{}) bool {
-	exception, ok := err.(BadRequestError)
-	if ok {
-		wrapper := helper.WebResponse{
-			Message: exception.Error,
-			Data:    nil,
-		}
-		helper.WriteToResponseBody(writer, wrapper, http.StatusBadRequest)
-		return true
-	} else {
-		return false
+	var validationErrors validator.ValidationErrors
+	if status == http.StatusBadRequest && errors.As(err, &validationErrors) {
+		webResponse.Errors = fieldErrors(validationErrors, locale)
 	}
+
+	helper.WriteToResponseBody(w, webResponse, status)
 }
-func validationError(writer http.ResponseWriter, request *http.Request, err interface{}) bool {
-	_, ok := err.(validator.ValidationErrors)
-	if ok {
-		message := "bad request"
-		// for _, e := range exception {
-		// 	message += fmt.Sprintf(" %s %s;", e.Field(), e.Tag())
-		// }
-		webResponse := helper.WebResponse{
-			Message: message,
-			Data:    nil,
-		}
 
-		helper.WriteToResponseBody(writer, webResponse, http.StatusBadRequest)
-		return true
-	} else {
-		return false
+func statusOf(r *http.Request, locale string, err error) (int, string) {
+	var httpErr *HTTPError
+	var validationErrors validator.ValidationErrors
+	var pqErr *pq.Error
+
+	// postgres reports statements canceled by a context or statement_timeout as query_canceled
+	queryCanceled := errors.As(err, &pqErr) && pqErr.Code == "57014"
+	timedOut := errors.Is(err, context.DeadlineExceeded) || queryCanceled
+
+	switch {
+	case errors.Is(err, context.Canceled) || timedOut && r.Context().Err() == context.Canceled:
+		return StatusClientClosedRequest, i18n.T(locale, "request_canceled")
+
+	case timedOut:
+		return http.StatusServiceUnavailable, i18n.T(locale, "request_timed_out")
+
+	case errors.As(err, &httpErr):
+		return httpErr.Status, i18n.T(locale, httpErr.Message)
+
+	case errors.As(err, &validationErrors):
+		return http.StatusBadRequest, i18n.T(locale, "bad_request")
 	}
-}
 
-func internalServerError(writer http.ResponseWriter, request *http.Request, err interface{}) {
-	log.Println(err)
+	var detailErr *helper.DetailError
+	for _, knownErr := range knownErrors {
+		if !errors.Is(err, knownErr.err) {
+			continue
+		}
 
-	webResponse := helper.WebResponse{
-		Message: "Internal server error",
-		Data:    nil,
+		if errors.As(err, &detailErr) {
+			return knownErr.status, i18n.T(locale, knownErr.key) + ": " + i18n.T(locale, detailErr.Key, detailErr.Params...)
+		}
+
+		// keep the detail added around the error, the name and value of an
+		// invalid parameter, which need no translation
+		detail := strings.TrimPrefix(err.Error(), knownErr.err.Error())
+		return knownErr.status, i18n.T(locale, knownErr.key) + detail
 	}
 
-	helper.WriteToResponseBody(writer, webResponse, http.StatusInternalServerError)
+	return http.StatusInternalServerError, i18n.T(locale, "internal_server_error")
 }
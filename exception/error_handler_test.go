@@ -0,0 +1,44 @@
+package exception
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/helper"
+)
+
+func TestStatusOfTranslatesBodyDetails(t *testing.T) {
+	config.Env.MAX_BODY_SIZE = 16
+
+	tests := []struct {
+		body    string
+		locale  string
+		status  int
+		message string
+	}{
+		{`{"zz":1}`, "en", http.StatusBadRequest, `invalid request body: unknown field "zz"`},
+		{`{"zz":1}`, "id", http.StatusBadRequest, `isi permintaan tidak valid: field "zz" tidak dikenal`},
+		{`{"name":1}`, "id", http.StatusBadRequest, `isi permintaan tidak valid: field "name" harus bertipe string`},
+		{`{"name":`, "id", http.StatusBadRequest, "isi permintaan tidak valid: JSON pada isi tidak lengkap"},
+		{`{} {}`, "id", http.StatusBadRequest, "isi permintaan tidak valid: isi harus berupa satu nilai JSON"},
+		{``, "id", http.StatusBadRequest, "isi permintaan tidak valid: isi kosong"},
+		{`{"name":"a very long name"}`, "id", http.StatusRequestEntityTooLarge, "isi permintaan terlalu besar: batasnya 16 byte"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tt.body))
+		r.Header.Set("Content-Type", "application/json")
+
+		err := helper.ParsingBody(httptest.NewRecorder(), r, &struct {
+			Name string `json:"name"`
+		}{})
+
+		status, message := statusOf(r, tt.locale, err)
+		if status != tt.status || message != tt.message {
+			t.Errorf("%s %q: got %d %q, want %d %q", tt.locale, tt.body, status, message, tt.status, tt.message)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package exception
+
+import "net/http"
+
+// HTTPError is an error carrying the response status and the i18n key of
+// the message shown to the client. Err is the underlying cause, it is only
+// logged.
+type HTTPError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	wrapped := *e
+	wrapped.Err = err
+
+	return &wrapped
+}
+
+func NewBadRequestError(message string) *HTTPError {
+	return &HTTPError{Status: http.StatusBadRequest, Message: message}
+}
+
+func NewUnauthorizedError(message string) *HTTPError {
+	return &HTTPError{Status: http.StatusUnauthorized, Message: message}
+}
+
+func NewForbiddenError(message string) *HTTPError {
+	return &HTTPError{Status: http.StatusForbidden, Message: message}
+}
+
+func NewNotFoundError(message string) *HTTPError {
+	return &HTTPError{Status: http.StatusNotFound, Message: message}
+}
+
+func NewTooManyRequestsError(message string) *HTTPError {
+	return &HTTPError{Status: http.StatusTooManyRequests, Message: message}
+}
+
+func NewConflictError(message string) *HTTPError {
+	return &HTTPError{Status: http.StatusConflict, Message: message}
+}
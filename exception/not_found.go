@@ -1,9 +0,0 @@
-package exception
-
-type NotFoundError struct {
-	Error string
-}
-
-func NewNotFoundError(error string) NotFoundError {
-	return NotFoundError{Error: error}
-}
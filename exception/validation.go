@@ -0,0 +1,39 @@
+package exception
+
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/cats-social/i18n"
+)
+
+// FieldError describes one invalid field of a request body. Field is the
+// JSON path of the field, e.g. "imageUrls[0]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+func fieldErrors(validationErrors validator.ValidationErrors, locale string) []FieldError {
+	trans := i18n.Translator(locale)
+	errs := make([]FieldError, 0, len(validationErrors))
+
+	for _, e := range validationErrors {
+		// the namespace starts with the struct name, e.g. CatInsertRequest.imageUrls[0]
+		field := e.Namespace()
+		if _, after, ok := strings.Cut(field, "."); ok {
+			field = after
+		}
+
+		errs = append(errs, FieldError{
+			Field:   field,
+			Rule:    e.Tag(),
+			Param:   e.Param(),
+			Message: e.Translate(trans),
+		})
+	}
+
+	return errs
+}
@@ -2,13 +2,102 @@ package helper
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/i18n"
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrBodyTooLarge         = errors.New("request body too large")
+	ErrInvalidBody          = errors.New("invalid request body")
 )
 
-func ParsingBody(request *http.Request, result interface{}) error {
-	decoder := json.NewDecoder(request.Body)
-	err := decoder.Decode(result)
-	return err
+// DetailError is Err with a detail for the client, the i18n message Key
+// rendered with Params in the locale of the request.
+type DetailError struct {
+	Err    error
+	Key    string
+	Params []string
+}
+
+func detailError(err error, key string, params ...string) *DetailError {
+	return &DetailError{Err: err, Key: key, Params: params}
+}
+
+func (e *DetailError) Error() string {
+	return e.Err.Error() + ": " + i18n.T(i18n.DefaultLocale, e.Key, e.Params...)
+}
+
+func (e *DetailError) Unwrap() error {
+	return e.Err
+}
+
+// ParsingBody decodes the JSON body of request into result. The body must be
+// sent as application/json, fit in MAX_BODY_SIZE bytes and hold exactly one
+// value without fields unknown to result.
+func ParsingBody(writer http.ResponseWriter, request *http.Request, result interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return detailError(ErrUnsupportedMediaType, "body_expected_json")
+	}
+
+	limit := config.Env.MAX_BODY_SIZE
+	decoder := json.NewDecoder(http.MaxBytesReader(writer, request.Body, limit))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(result)
+	if err != nil {
+		return decodeError(err, limit)
+	}
+
+	err = decoder.Decode(&struct{}{})
+	if err != io.EOF {
+		if err == nil {
+			return detailError(ErrInvalidBody, "body_single_value")
+		}
+		return decodeError(err, limit)
+	}
+
+	return nil
+}
+
+func decodeError(err error, limit int64) error {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var maxBytesError *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesError):
+		return detailError(ErrBodyTooLarge, "body_limit", strconv.FormatInt(limit, 10))
+
+	case errors.Is(err, io.EOF):
+		return detailError(ErrInvalidBody, "body_empty")
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return detailError(ErrInvalidBody, "body_incomplete")
+
+	case errors.As(err, &syntaxError):
+		return detailError(ErrInvalidBody, "body_malformed", strconv.FormatInt(syntaxError.Offset, 10))
+
+	case errors.As(err, &typeError):
+		return detailError(ErrInvalidBody, "body_field_type", strconv.Quote(typeError.Field), typeError.Type.String())
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return detailError(ErrInvalidBody, "body_unknown_field", field)
+	}
+
+	return fmt.Errorf("%w: %w", detailError(ErrInvalidBody, "body_not_json"), err)
 }
 
 func WriteToResponseBody(writer http.ResponseWriter, response interface{}, statusCode int) {
@@ -18,11 +107,14 @@ func WriteToResponseBody(writer http.ResponseWriter, response interface{}, statu
 
 	encoder := json.NewEncoder(writer)
 	err := encoder.Encode(response)
-
-	PanicIfError(err)
+	if err != nil {
+		// the status is already sent, nothing else can be told to the client
+		log.Println("Error writing response:", err)
+	}
 }
 
 type WebResponse struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
+	Errors  interface{} `json:"errors,omitempty"`
 }
@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/malikfajr/cats-social/config"
+)
+
+// ClientIP returns the address of the client of r. Behind TRUSTED_PROXY_HOPS
+// proxies the client is read from X-Forwarded-For, counting the hops from
+// the right since the leftmost entries are set by the client itself.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	hops := config.Env.TRUSTED_PROXY_HOPS
+	if hops <= 0 {
+		return host
+	}
+
+	forwarded := []string{}
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(value, ",") {
+			forwarded = append(forwarded, strings.TrimSpace(addr))
+		}
+	}
+
+	if len(forwarded) < hops {
+		return host
+	}
+
+	if ip := net.ParseIP(forwarded[len(forwarded)-hops]); ip != nil {
+		return ip.String()
+	}
+
+	return host
+}
+
+// UserAgent returns the User-Agent of r, cut to 255 characters.
+func UserAgent(r *http.Request) string {
+	ua := []rune(r.UserAgent())
+	return string(ua[:min(len(ua), 255)])
+}
@@ -0,0 +1,260 @@
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+)
+
+type RoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+type adminUser struct {
+	Id         string     `json:"id"`
+	Email      string     `json:"email"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	DisabledAt *time.Time `json:"disabledAt"`
+}
+
+// audit records an admin action inside the transaction of the action itself.
+// The actor is the logged in user, with the email it has now rather than the
+// one its token was issued with.
+func audit(ctx context.Context, tx models.Tx, action string, targetType string, targetId string, detail string) error {
+	principal, _ := auth.FromContext(ctx)
+
+	actor, err := tx.Users().GetUserById(ctx, principal.Id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Audit().Record(ctx, models.AuditEntry{
+		ActorId:    actor.Id,
+		ActorEmail: actor.Email,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Detail:     detail,
+	})
+}
+
+func AdminListUsers(w http.ResponseWriter, r *http.Request) error {
+	userParam := models.UserParam{
+		Search:      r.URL.Query().Get("search"),
+		Role:        r.URL.Query().Get("role"),
+		DisabledStr: r.URL.Query().Get("disabled"),
+		Limit:       r.URL.Query().Get("limit"),
+		Offset:      r.URL.Query().Get("offset"),
+	}
+
+	var users []models.User
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) (err error) {
+		users, err = tx.Users().SearchUsers(r.Context(), userParam)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	data := make([]adminUser, 0, len(users))
+	for _, user := range users {
+		data = append(data, adminUser{Id: user.Id, Email: user.Email, Name: user.Name, Role: user.Role, DisabledAt: user.DisabledAt})
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    data,
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// AdminSetRole changes the role of a user. Its sessions are revoked so the
+// new role is in effect on the next login instead of the next refresh.
+func AdminSetRole(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	bodyRequest := RoleRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	principal, _ := auth.FromContext(r.Context())
+
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		if user.Id == principal.Id {
+			return exception.NewBadRequestError("admin_self_action")
+		}
+
+		err = tx.Users().SetUserRole(r.Context(), user.Id, bodyRequest.Role)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Sessions().RevokeUserSessions(r.Context(), user.Id, "")
+		if err != nil {
+			return err
+		}
+
+		return audit(r.Context(), tx, "user.role", "user", user.Id, "email "+user.Email+", "+user.Role+" -> "+bodyRequest.Role)
+	})
+	if err != nil {
+		return err
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "success"}, http.StatusOK)
+	return nil
+}
+
+func AdminDisableUser(w http.ResponseWriter, r *http.Request) error {
+	return setUserDisabled(w, r, true)
+}
+
+func AdminEnableUser(w http.ResponseWriter, r *http.Request) error {
+	return setUserDisabled(w, r, false)
+}
+
+// setUserDisabled blocks or unblocks the login of a user, disabling also
+// revokes every session of the user.
+func setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) error {
+	id := r.PathValue("id")
+	principal, _ := auth.FromContext(r.Context())
+
+	action := "user.enable"
+	if disabled {
+		action = "user.disable"
+	}
+
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		if user.Id == principal.Id {
+			return exception.NewBadRequestError("admin_self_action")
+		}
+
+		err = tx.Users().SetUserDisabled(r.Context(), user.Id, disabled)
+		if err != nil {
+			return err
+		}
+
+		if disabled {
+			err = tx.Sessions().RevokeUserSessions(r.Context(), user.Id, "")
+			if err != nil {
+				return err
+			}
+		}
+
+		return audit(r.Context(), tx, action, "user", user.Id, "email "+user.Email)
+	})
+	if err != nil {
+		return err
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "success"}, http.StatusOK)
+	return nil
+}
+
+// AdminDestroyCat deletes a cat of any owner, its matches are deleted with it.
+func AdminDestroyCat(w http.ResponseWriter, r *http.Request) error {
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		cat, err := findCat(r.Context(), tx, r.PathValue("id"))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Cats().DestroyCatById(r.Context(), cat.Id)
+		if err != nil {
+			return err
+		}
+
+		owner := cat.UserId
+		if owner == "" {
+			owner = "none"
+		}
+
+		return audit(r.Context(), tx, "cat.delete", "cat", cat.Id, "owner "+owner+", name "+cat.Name)
+	})
+	if errors.Is(err, models.ErrCatNotFound) {
+		return exception.NewNotFoundError("cat_id_not_found").Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "success"}, http.StatusOK)
+	return nil
+}
+
+// AdminCancelMatch deletes a pending match of any issuer.
+func AdminCancelMatch(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		status, issuerId, err := tx.Matches().DeleteMatch(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		if status != "pending" {
+			return exception.NewBadRequestError("match_already_processed")
+		}
+
+		return audit(r.Context(), tx, "match.cancel", "match", id, "issuer "+issuerId)
+	})
+	if err != nil {
+		return matchError(err)
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "success"}, http.StatusOK)
+	return nil
+}
+
+func AdminListAudit(w http.ResponseWriter, r *http.Request) error {
+	auditParam := models.AuditParam{
+		ActorId:    r.URL.Query().Get("actorId"),
+		ActorEmail: r.URL.Query().Get("actorEmail"),
+		Action:     r.URL.Query().Get("action"),
+		TargetId:   r.URL.Query().Get("targetId"),
+		Limit:      r.URL.Query().Get("limit"),
+		Offset:     r.URL.Query().Get("offset"),
+	}
+
+	var entries []models.AuditEntry
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) (err error) {
+		entries, err = tx.Audit().GetAllAudit(r.Context(), auditParam)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    entries,
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
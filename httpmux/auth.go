@@ -1,48 +1,140 @@
 package httpmux
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
-	"time"
+	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/auth"
 	"github.com/malikfajr/cats-social/exception"
 	"github.com/malikfajr/cats-social/helper"
 	"github.com/malikfajr/cats-social/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/malikfajr/cats-social/password"
 )
 
 type Credential struct {
-	Email    string `json:"email" validate:"email"`
-	Password string `json:"password" validate:"min=5,max=15"`
+	Email    string `json:"email" validate:"email,max=50"`
+	Password string `json:"password" validate:"required"`
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func LoginHandler(w http.ResponseWriter, r *http.Request) error {
 	credential := Credential{}
 
-	json.NewDecoder(r.Body).Decode(&credential)
+	err := helper.ParsingBody(w, r, &credential)
+	if err != nil {
+		return err
+	}
 
-	err := validate.Struct(credential)
-	helper.PanicIfError(err)
+	err = validate.Struct(credential)
+	if err != nil {
+		return err
+	}
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
+	ip := helper.ClientIP(r)
+
+	var user models.User
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		attempts, err := loginAttempts(r.Context(), tx, credential.Email, ip)
+		if err != nil {
+			return err
+		}
+
+		err = checkLoginAllowed(w, attempts)
+		if err != nil {
+			return err
+		}
+
+		user, err = tx.Users().GetUserByEmail(r.Context(), credential.Email)
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
-	user, err := models.GetUserByEmail(r.Context(), tx, credential.Email)
+	// unknown emails and wrong passwords fail alike, in the same time
+	ok, rehash, err := comparePassword(user.Password, credential.Password)
 	if err != nil {
-		panic(exception.NewNotFoundError(err.Error()))
+		return err
+	}
+
+	if !ok {
+		err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+			return recordLoginFailure(r.Context(), tx, credential.Email, ip)
+		})
+		if err != nil {
+			return err
+		}
+
+		return exception.NewBadRequestError("credentials_invalid")
+	}
+
+	if user.DisabledAt != nil {
+		return exception.NewForbiddenError("account_disabled")
+	}
+
+	// upgrade the hash to the configured algorithm while the password is known
+	var newHash string
+	if rehash {
+		newHash, err = password.Hash(credential.Password)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(credential.Password))
+	var tokens tokenPair
+	var challenge string
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		if newHash != "" {
+			err = tx.Users().SetUserPassword(r.Context(), user.Id, newHash)
+			if err != nil {
+				return err
+			}
+		}
+
+		// the failed attempts stay counted until the second factor is checked too
+		if user.TOTPEnabledAt != nil {
+			challenge, err = newMFAChallenge(r.Context(), tx, user)
+			return err
+		}
+
+		err = tx.LoginAttempts().ResetLoginAttempt(r.Context(), models.AttemptScopeAccount, strings.ToLower(user.Email))
+		if err != nil {
+			return err
+		}
+
+		tokens, err = issueTokens(r, tx, user, "")
+		return err
+	})
 	if err != nil {
-		panic(exception.NewBadRequestError("Password wrong"))
+		return err
+	}
+
+	if challenge != "" {
+		wrapper := helper.WebResponse{
+			Message: "MFA code required",
+			Data: map[string]interface{}{
+				"mfaRequired": true,
+				"mfaToken":    challenge,
+			},
+		}
+
+		helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+		return nil
 	}
 
+	writeLoggedIn(w, user, tokens)
+	return nil
+}
+
+func writeLoggedIn(w http.ResponseWriter, user models.User, tokens tokenPair) {
 	data := map[string]string{
-		"email":       user.Email,
-		"name":        user.Name,
-		"accessToken": generateToken(user.Email, user.Name),
+		"email":        user.Email,
+		"name":         user.Name,
+		"accessToken":  tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
 	}
 
 	wrapper := helper.WebResponse{
@@ -53,35 +145,57 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
 }
 
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+func RegisterHandler(w http.ResponseWriter, r *http.Request) error {
 	user := models.User{}
 
 	// parsing body
-	json.NewDecoder(r.Body).Decode(&user)
+	err := helper.ParsingBody(w, r, &user)
+	if err != nil {
+		return err
+	}
 
 	// validation json
-	err := validate.Struct(user)
-	helper.PanicIfError(err)
-
-	hashPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), config.Env.BCRYPT_SALT)
-	helper.PanicIfError(err)
-
-	user.Password = string(hashPassword)
+	err = validate.Struct(user)
+	if err != nil {
+		return err
+	}
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
+	user.Password, err = password.Hash(user.Password)
+	if err != nil {
+		return err
+	}
 
-	_, err = models.GetUserByEmail(r.Context(), tx, user.Email)
-	if err == nil {
-		panic(exception.NewConflictError("Email has taken"))
+	var newUser models.User
+	var tokens tokenPair
+	var verificationToken string
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		newUser, err = tx.Users().SaveUser(r.Context(), user)
+		if err != nil {
+			return err
+		}
+
+		verificationToken, err = newVerificationToken(r.Context(), tx, newUser)
+		if err != nil {
+			return err
+		}
+
+		tokens, err = issueTokens(r, tx, newUser, "")
+		return err
+	})
+	if errors.Is(err, models.ErrEmailTaken) {
+		return exception.NewConflictError("email_taken").Wrap(err)
+	}
+	if err != nil {
+		return err
 	}
 
-	newUser := models.SaveUser(r.Context(), tx, user)
+	sendVerificationMail(r, newUser.Email, verificationToken)
 
 	data := map[string]string{
-		"email":       newUser.Email,
-		"name":        newUser.Name,
-		"accessToken": generateToken(newUser.Email, newUser.Name),
+		"email":        newUser.Email,
+		"name":         newUser.Name,
+		"accessToken":  tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
 	}
 
 	wrapper := helper.WebResponse{
@@ -90,22 +204,10 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	helper.WriteToResponseBody(w, wrapper, http.StatusCreated)
-}
-
-func generateToken(email string, name string) string {
-	myClaims := config.CustomJWTClaim{
-		Email: email,
-		Name:  name,
-		Exp:   jwt.NewNumericDate(time.Now().Add(8 * time.Hour)),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, myClaims)
-
-	ss, err := token.SignedString([]byte(config.Env.JWT_SECRET))
-	helper.PanicIfError(err)
-
-	return ss
+	return nil
 }
 
 func Check(w http.ResponseWriter, r *http.Request) {
-	w.Write([]byte("Protected" + r.Header.Get("email")))
+	principal, _ := auth.FromContext(r.Context())
+	w.Write([]byte("Protected" + principal.Email))
 }
@@ -1,11 +1,16 @@
 package httpmux
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/config"
 	"github.com/malikfajr/cats-social/exception"
 	"github.com/malikfajr/cats-social/helper"
 	"github.com/malikfajr/cats-social/models"
@@ -29,37 +34,113 @@ var SexEnum = map[string]bool{
 	"female": true,
 }
 
-func SaveCat(w http.ResponseWriter, r *http.Request) {
+// catsResponse is a page of cats with the cursors of the pages around it.
+type catsResponse struct {
+	helper.WebResponse
+	NextCursor *string   `json:"nextCursor"`
+	PrevCursor *string   `json:"prevCursor"`
+	Meta       *catsMeta `json:"meta,omitempty"`
+}
+
+// catsMeta describes a page of cats, sent with ?meta=true. Offset is set in
+// the legacy paging, Cursor when a cursor was given.
+type catsMeta struct {
+	Total     int     `json:"total"`
+	Estimated bool    `json:"estimated"`
+	Limit     int     `json:"limit"`
+	Offset    *int    `json:"offset,omitempty"`
+	Cursor    *string `json:"cursor,omitempty"`
+	HasMore   bool    `json:"hasMore"`
+}
+
+// pageLinks is the RFC 8288 Link header of the pages around page, the
+// request with its cursor replaced.
+func pageLinks(r *http.Request, page models.CatPage) string {
+	links := []string{}
+	for _, link := range []struct{ rel, cursor string }{{"next", page.NextCursor}, {"prev", page.PrevCursor}} {
+		if link.cursor == "" {
+			continue
+		}
+
+		query := r.URL.Query()
+		query.Del("offset")
+		query.Set("cursor", link.cursor)
+		links = append(links, fmt.Sprintf(`<%s%s?%s>; rel="%s"`, config.Env.API_URL, r.URL.Path, query.Encode(), link.rel))
+	}
+
+	return strings.Join(links, ", ")
+}
+
+// nullable turns an empty string into a JSON null.
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+// findCat finds a cat by its ULID or, while CAT_NUMERIC_IDS is on, by the
+// sequential id cats were exposed with before.
+func findCat(ctx context.Context, tx models.Tx, id string) (models.Cat, error) {
+	if legacyId, err := strconv.Atoi(id); err == nil && config.Env.CAT_NUMERIC_IDS {
+		return tx.Cats().GetCatByLegacyId(ctx, legacyId)
+	}
+
+	return tx.Cats().GetCatById(ctx, id)
+}
+
+func SaveCat(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
 	catRequest := models.CatInsertRequest{}
 
-	json.NewDecoder(r.Body).Decode(&catRequest)
+	err := helper.ParsingBody(w, r, &catRequest)
+	if err != nil {
+		return err
+	}
 
-	err := validate.Struct(catRequest)
-	helper.PanicIfError(err)
+	err = validate.Struct(catRequest)
+	if err != nil {
+		return err
+	}
 
-	catRequest.UserEmail = r.Header.Get("email")
+	catRequest.UserId = principal.Id
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
-	id, date := models.SaveCat(r.Context(), tx, catRequest)
+	var id string
+	var date time.Time
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		err := requireVerifiedEmail(r.Context(), tx, catRequest.UserId, "cat")
+		if err != nil {
+			return err
+		}
+
+		id, date, err = tx.Cats().SaveCat(r.Context(), catRequest)
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
 	wraper := helper.WebResponse{
 		Message: "success",
 		Data: map[string]interface{}{
-			"id":        fmt.Sprintf("%d", id),
+			"id":        id,
 			"createdAt": date,
 		},
 	}
 
 	helper.WriteToResponseBody(w, wraper, http.StatusCreated)
+	return nil
 }
 
-func GetCat(w http.ResponseWriter, r *http.Request) {
-	var data []models.Cat = []models.Cat{}
+func GetCat(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	page := models.CatPage{Cats: []models.Cat{}}
 	catParam := models.CatParam{
 		Id:            r.URL.Query().Get("id"),
 		Owned:         r.URL.Query().Get("owned"),
-		Email:         r.Header.Get("email"),
+		UserId:        principal.Id,
+		ViewerId:      principal.Id,
 		AgeStr:        r.URL.Query().Get("ageInMonth"),
 		HasMatchedStr: r.URL.Query().Get("hasMatched"),
 		Race:          r.URL.Query().Get("race"),
@@ -67,6 +148,8 @@ func GetCat(w http.ResponseWriter, r *http.Request) {
 		Search:        r.URL.Query().Get("search"),
 		Limit:         r.URL.Query().Get("limit"),
 		Offsset:       r.URL.Query().Get("offset"),
+		Cursor:        r.URL.Query().Get("cursor"),
+		Sort:          r.URL.Query().Get("sort"),
 	}
 
 	if ok := RaceEnum[catParam.Race]; ok == false {
@@ -77,33 +160,84 @@ func GetCat(w http.ResponseWriter, r *http.Request) {
 		catParam.Sex = ""
 	}
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
+	withMeta, _ := strconv.ParseBool(r.URL.Query().Get("meta"))
+	estimate := r.URL.Query().Get("count") == "estimate"
+
+	total := 0
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) (err error) {
+		if catParam.Id != "" {
+			cat, err := findCat(r.Context(), tx, catParam.Id)
+			if errors.Is(err, models.ErrCatNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			catParam.Id = cat.Id
+		}
+
+		page, err = tx.Cats().GetAllCat(r.Context(), catParam)
+		if err != nil || !withMeta {
+			return err
+		}
+
+		total, err = tx.Cats().CountCat(r.Context(), catParam, estimate)
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
-	data = models.GetAllCat(r.Context(), tx, catParam)
+	wrapper := catsResponse{
+		WebResponse: helper.WebResponse{
+			Message: "success",
+			Data:    page.Cats,
+		},
+		NextCursor: nullable(page.NextCursor),
+		PrevCursor: nullable(page.PrevCursor),
+	}
 
-	wrapper := helper.WebResponse{
-		Message: "success",
-		Data:    data,
+	if withMeta {
+		wrapper.Meta = &catsMeta{
+			Total:     total,
+			Estimated: estimate,
+			Limit:     page.Limit,
+			HasMore:   page.NextCursor != "",
+		}
+
+		if catParam.Cursor != "" {
+			wrapper.Meta.Cursor = &catParam.Cursor
+		} else {
+			wrapper.Meta.Offset = &page.Offset
+		}
+	}
+
+	if links := pageLinks(r, page); links != "" {
+		w.Header().Set("Link", links)
 	}
 
 	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
 }
 
-func DestroyCat(w http.ResponseWriter, r *http.Request) {
-	email := r.Header.Get("email")
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		panic(exception.NewNotFoundError("id not found"))
-	}
+func DestroyCat(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	userId := principal.Id
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		cat, err := findCat(r.Context(), tx, r.PathValue("id"))
+		if err != nil {
+			return err
+		}
 
-	err = models.DestroyCat(r.Context(), tx, id, email)
+		return tx.Cats().DestroyCat(r.Context(), cat.Id, userId)
+	})
+	if errors.Is(err, models.ErrCatNotFound) {
+		return exception.NewNotFoundError("cat_id_not_found").Wrap(err)
+	}
 	if err != nil {
-		panic(exception.NewNotFoundError("id is not found"))
+		return err
 	}
 
 	wrapper := helper.WebResponse{
@@ -112,55 +246,73 @@ func DestroyCat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
 }
 
-func UpdateCat(w http.ResponseWriter, r *http.Request) {
-	email := r.Header.Get("email")
-	catRequest := models.CatInsertRequest{}
+func UpdateCat(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	userId := principal.Id
+	bodyRequest := models.CatUpdateRequest{}
 
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
+	err := helper.ParsingBody(w, r, &bodyRequest)
 	if err != nil {
-		panic(exception.NewNotFoundError("id is not found"))
+		return err
 	}
 
-	json.NewDecoder(r.Body).Decode(&catRequest)
-
-	err = validate.Struct(catRequest)
-	helper.PanicIfError(err)
-
-	catRequest.UserEmail = r.Header.Get("email")
-
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
-
-	cat, err := models.GetCatById(r.Context(), tx, id)
+	err = validate.Struct(bodyRequest)
 	if err != nil {
-		panic(exception.NewNotFoundError("id is not found"))
+		return err
 	}
 
-	if cat.UserEmail != email {
-		panic(exception.NewNotFoundError("id is not found"))
+	catRequest := bodyRequest.CatInsertRequest
+	catRequest.UserId = userId
+
+	var id string
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		cat, err := findCat(r.Context(), tx, r.PathValue("id"))
+		if err != nil {
+			return err
+		}
+
+		if cat.UserId != userId {
+			return models.ErrCatNotFound
+		}
+
+		id = cat.Id
+		catRequest.Private = cat.Private
+		if bodyRequest.Private != nil {
+			catRequest.Private = *bodyRequest.Private
+		}
+
+		exist, err := tx.Matches().CountCatInMatch(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		if exist > 0 && catRequest.Sex != cat.Sex {
+			return exception.NewBadRequestError("cat_sex_locked")
+		}
+
+		if exist > 0 {
+			return tx.Cats().UpdateCatWithoutSex(r.Context(), id, catRequest)
+		}
+
+		return tx.Cats().UpdateCatWithSex(r.Context(), id, catRequest)
+	})
+	if errors.Is(err, models.ErrCatNotFound) {
+		return exception.NewNotFoundError("cat_id_not_found").Wrap(err)
 	}
-
-	exist := models.CountCatInMatch(r.Context(), tx, idStr)
-
-	if exist > 0 && catRequest.Sex != cat.Sex {
-		panic(exception.NewBadRequestError("Cannot update sex when cat requested to match"))
-	}
-
-	if exist > 0 {
-		_ = models.UpdateCatWithoutSex(r.Context(), tx, id, catRequest)
-	} else {
-		_ = models.UpdateCatWithSex(r.Context(), tx, id, catRequest)
+	if err != nil {
+		return err
 	}
 
 	wraper := helper.WebResponse{
 		Message: "success",
 		Data: map[string]interface{}{
-			"id": fmt.Sprintf("%d", id),
+			"id": id,
 		},
 	}
 
 	helper.WriteToResponseBody(w, wraper, http.StatusOK)
+	return nil
 }
@@ -0,0 +1,156 @@
+package httpmux
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+)
+
+type EmailChangeRequest struct {
+	Email    string `json:"email" validate:"required,email,max=50"`
+	Password string `json:"password" validate:"required"`
+}
+
+// ChangeEmail mails a confirmation link to the new address of the logged in
+// user. The email only changes once the link is opened, see
+// ConfirmEmailChange.
+func ChangeEmail(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	bodyRequest := EmailChangeRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = confirmPassword(w, r, principal.Id, bodyRequest.Password)
+	if err != nil {
+		return err
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), principal.Id)
+		if err != nil {
+			return err
+		}
+
+		if strings.EqualFold(user.Email, bodyRequest.Email) {
+			return exception.NewBadRequestError("email_unchanged")
+		}
+
+		_, err = tx.Users().GetUserByEmail(r.Context(), bodyRequest.Email)
+		if err == nil {
+			return exception.NewConflictError("email_taken")
+		}
+		if !errors.Is(err, models.ErrUserNotFound) {
+			return err
+		}
+
+		// only the latest link works
+		err = tx.UserTokens().DeleteUserTokens(r.Context(), user.Id, models.PurposeEmailChange)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.UserTokens().NewUserToken(r.Context(), models.UserToken{
+			UserId:    user.Id,
+			Email:     bodyRequest.Email,
+			Purpose:   models.PurposeEmailChange,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(config.Env.EMAIL_VERIFICATION_TTL),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	link := config.Env.API_URL + "/v1/user/email/confirm?token=" + token
+	hours := strconv.Itoa(int(math.Ceil(config.Env.EMAIL_VERIFICATION_TTL.Hours())))
+	sendMail(r, bodyRequest.Email, "mail_email_change", hours, link)
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "Confirmation email sent to the new address"}, http.StatusAccepted)
+	return nil
+}
+
+// ConfirmEmailChange is the target of the link mailed by ChangeEmail. The
+// new address counts as verified and the old one is told about the change.
+func ConfirmEmailChange(w http.ResponseWriter, r *http.Request) error {
+	invalidToken := exception.NewBadRequestError("email_change_token_invalid")
+
+	tokenString := r.URL.Query().Get("token")
+	if tokenString == "" {
+		return invalidToken
+	}
+
+	var oldEmail, newEmail string
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		token, err := tx.UserTokens().GetUserTokenByHash(r.Context(), models.PurposeEmailChange, hashToken(tokenString))
+		if errors.Is(err, models.ErrTokenNotFound) {
+			return invalidToken.Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			return invalidToken
+		}
+
+		user, err := tx.Users().GetUserById(r.Context(), token.UserId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.UserTokens().UseUserToken(r.Context(), token.Id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Users().SetUserEmail(r.Context(), user.Id, token.Email)
+		if errors.Is(err, models.ErrEmailTaken) {
+			return exception.NewConflictError("email_taken").Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		// the link is opened without a login, the user is the actor
+		oldEmail, newEmail = user.Email, token.Email
+		return tx.Audit().Record(r.Context(), models.AuditEntry{
+			ActorId:    user.Id,
+			ActorEmail: oldEmail,
+			Action:     "user.email",
+			TargetType: "user",
+			TargetId:   user.Id,
+			Detail:     oldEmail + " -> " + newEmail,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	sendMail(r, oldEmail, "mail_email_changed", newEmail)
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "Email changed successfully"}, http.StatusOK)
+	return nil
+}
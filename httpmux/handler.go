@@ -0,0 +1,17 @@
+package httpmux
+
+import (
+	"net/http"
+
+	"github.com/malikfajr/cats-social/exception"
+)
+
+// Handler is an http.Handler that returns its error instead of writing it,
+// the response for the error is written by exception.WriteError.
+type Handler func(w http.ResponseWriter, r *http.Request) error
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		exception.WriteError(w, r, err)
+	}
+}
@@ -0,0 +1,220 @@
+package httpmux
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+	"github.com/malikfajr/cats-social/password"
+)
+
+// loginLimit is the failed login policy of an attempt scope: the first free
+// failures cost nothing, then every failure doubles the wait before the next
+// try, and lockout failures lock the key for LOGIN_LOCKOUT_DURATION.
+type loginLimit struct {
+	free    int
+	lockout int
+}
+
+func limitOf(scope string) loginLimit {
+	if scope == models.AttemptScopeIP {
+		return loginLimit{free: config.Env.LOGIN_IP_FREE_ATTEMPTS, lockout: config.Env.LOGIN_IP_LOCKOUT_ATTEMPTS}
+	}
+
+	return loginLimit{free: config.Env.LOGIN_FREE_ATTEMPTS, lockout: config.Env.LOGIN_LOCKOUT_ATTEMPTS}
+}
+
+func (l loginLimit) locked(attempt models.LoginAttempt) bool {
+	return l.lockout > 0 && attempt.Failures >= l.lockout
+}
+
+func (l loginLimit) backoff(failures int) time.Duration {
+	n := failures - l.free
+	if n <= 0 {
+		return 0
+	}
+
+	// the wait is capped long before the shift could overflow
+	backoff := config.Env.LOGIN_BACKOFF_BASE << min(n-1, 30)
+	return min(backoff, config.Env.LOGIN_BACKOFF_MAX)
+}
+
+// loginAttempts returns the counters of the account and of the client
+// address a login is tried with.
+func loginAttempts(ctx context.Context, tx models.Tx, email string, ip string) ([]models.LoginAttempt, error) {
+	account, err := tx.LoginAttempts().GetLoginAttempt(ctx, models.AttemptScopeAccount, strings.ToLower(email))
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := tx.LoginAttempts().GetLoginAttempt(ctx, models.AttemptScopeIP, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return []models.LoginAttempt{account, client}, nil
+}
+
+// checkLoginAllowed answers 429 with Retry-After while any counter waits for
+// its backoff or lockout to end.
+func checkLoginAllowed(w http.ResponseWriter, attempts []models.LoginAttempt) error {
+	now := time.Now()
+
+	var wait time.Duration
+	key := "login_throttled"
+	for _, attempt := range attempts {
+		remaining := attempt.LockedUntil.Sub(now)
+		if remaining <= 0 {
+			continue
+		}
+
+		wait = max(wait, remaining)
+		if limitOf(attempt.Scope).locked(attempt) {
+			key = "login_locked"
+		}
+	}
+
+	if wait <= 0 {
+		return nil
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return exception.NewTooManyRequestsError(key)
+}
+
+// recordLoginFailure counts a failed login on the counters of the account
+// and of the client address and records it for security review, together
+// with the lockouts it causes. The backoff follows the count the store
+// returns, which includes the failures of concurrent logins.
+func recordLoginFailure(ctx context.Context, tx models.Tx, email string, ip string) error {
+	now := time.Now()
+	// failures are forgotten after a quiet window or once a lockout ended
+	since := now.Add(-config.Env.LOGIN_ATTEMPT_WINDOW)
+
+	var failures int
+	keys := []struct{ scope, key string }{
+		{models.AttemptScopeAccount, strings.ToLower(email)},
+		{models.AttemptScopeIP, ip},
+	}
+	for _, key := range keys {
+		limit := limitOf(key.scope)
+
+		attempt, err := tx.LoginAttempts().AddLoginFailure(ctx, key.scope, key.key, now, since, limit.lockout)
+		if err != nil {
+			return err
+		}
+
+		lockedUntil := now.Add(limit.backoff(attempt.Failures))
+
+		locked := limit.locked(attempt)
+		if locked {
+			lockedUntil = now.Add(config.Env.LOGIN_LOCKOUT_DURATION)
+		}
+
+		err = tx.LoginAttempts().LockLoginAttempt(ctx, key.scope, key.key, lockedUntil)
+		if err != nil {
+			return err
+		}
+
+		if locked {
+			err = recordLoginEvent(ctx, tx, "login_locked", email, key.scope, key.key,
+				fmt.Sprintf("ip=%s failures=%d until=%s", ip, attempt.Failures, lockedUntil.Format(time.RFC3339)))
+			if err != nil {
+				return err
+			}
+		}
+
+		if key.scope == models.AttemptScopeAccount {
+			failures = attempt.Failures
+		}
+	}
+
+	return recordLoginEvent(ctx, tx, "login_failed", email, models.AttemptScopeAccount, strings.ToLower(email),
+		fmt.Sprintf("ip=%s failures=%d", ip, failures))
+}
+
+// recordLoginEvent audits a login event, the actor is the email the login
+// was tried with, whether or not such an account exists.
+func recordLoginEvent(ctx context.Context, tx models.Tx, action string, email string, targetType string, targetId string, detail string) error {
+	return tx.Audit().Record(ctx, models.AuditEntry{
+		ActorEmail: email,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Detail:     detail,
+	})
+}
+
+// confirmPassword checks the password of the logged in user before a
+// sensitive change. It is throttled and counted on the same counters as the
+// logins of the account, so a stolen access token cannot be used to guess
+// the password either.
+func confirmPassword(w http.ResponseWriter, r *http.Request, userId string, plain string) error {
+	ip := helper.ClientIP(r)
+
+	var user models.User
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) (err error) {
+		user, err = tx.Users().GetUserById(r.Context(), userId)
+		if err != nil {
+			return err
+		}
+
+		attempts, err := loginAttempts(r.Context(), tx, user.Email, ip)
+		if err != nil {
+			return err
+		}
+
+		return checkLoginAllowed(w, attempts)
+	})
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := password.Verify(user.Password, plain)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+			return recordLoginFailure(r.Context(), tx, user.Email, ip)
+		})
+		if err != nil {
+			return err
+		}
+
+		return exception.NewBadRequestError("password_wrong")
+	}
+
+	return nil
+}
+
+var dummyHash struct {
+	once sync.Once
+	hash string
+}
+
+// comparePassword checks plain against hash and reports whether hash should
+// be upgraded. An empty hash, e.g. of an unknown email, is checked against a
+// dummy hash of the configured hasher so both take the same time.
+func comparePassword(hash string, plain string) (ok bool, rehash bool, err error) {
+	if hash == "" {
+		dummyHash.once.Do(func() {
+			dummyHash.hash, _ = password.Hash("cats-social")
+		})
+
+		password.Verify(dummyHash.hash, plain)
+		return false, false, nil
+	}
+
+	return password.Verify(hash, plain)
+}
@@ -0,0 +1,42 @@
+package httpmux
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/malikfajr/cats-social/i18n"
+	"github.com/malikfajr/cats-social/mailer"
+)
+
+// mailTimeout bounds the delivery of one mail, which outlives its request.
+const mailTimeout = 30 * time.Second
+
+var mail mailer.Mailer
+
+func InitMailer(m mailer.Mailer) {
+	mail = m
+}
+
+// sendMail delivers the mail named key in the locale of r without waiting
+// for it, so the response time does not reveal whether a mail was sent. The
+// catalog holds the subject as key_subject and the body as key_body.
+func sendMail(r *http.Request, to string, key string, params ...string) {
+	locale := i18n.FromRequest(r)
+	msg := mailer.Message{
+		To:      to,
+		Subject: i18n.T(locale, key+"_subject"),
+		Body:    i18n.T(locale, key+"_body", params...),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		err := mail.Send(ctx, msg)
+		if err != nil {
+			log.Printf("sending %s to %s: %v", key, to, err)
+		}
+	}()
+}
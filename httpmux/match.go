@@ -1,124 +1,121 @@
 package httpmux
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 	"time"
 
+	"github.com/malikfajr/cats-social/auth"
 	"github.com/malikfajr/cats-social/exception"
 	"github.com/malikfajr/cats-social/helper"
 	"github.com/malikfajr/cats-social/models"
 )
 
-func CreateMatch(w http.ResponseWriter, r *http.Request) {
-	email := r.Header.Get("email")
-	username := r.Header.Get("name")
+func CreateMatch(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	userId := principal.Id
 	matchBody := models.MatchInsertRequest{}
 
-	json.NewDecoder(r.Body).Decode(&matchBody)
-
-	err := validate.Struct(matchBody)
-	helper.PanicIfError(err)
-
-	issuerCatId, err := strconv.Atoi(matchBody.UserCatId)
-	if err != nil {
-		panic(exception.NewNotFoundError("user cat id not found"))
-	}
-
-	receiverCatId, err := strconv.Atoi(matchBody.MatchCatId)
+	err := helper.ParsingBody(w, r, &matchBody)
 	if err != nil {
-		panic(exception.NewNotFoundError("match cat id not found"))
+		return err
 	}
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
-
-	issuerCat, err := models.GetCatById(r.Context(), tx, issuerCatId)
+	err = validate.Struct(matchBody)
 	if err != nil {
-		panic(exception.NewBadRequestError("user cat id not found"))
-	}
-
-	if issuerCat.UserEmail != email {
-		panic(exception.NewNotFoundError("userCatId is not belong to the user"))
+		return err
 	}
 
-	receiverCat, err := models.GetCatById(r.Context(), tx, receiverCatId)
+	matchBody.IssuerId = userId
+
+	var id string
+	var createdAt time.Time
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		err := requireVerifiedEmail(r.Context(), tx, userId, "match")
+		if err != nil {
+			return err
+		}
+
+		issuerCat, err := findCat(r.Context(), tx, matchBody.UserCatId)
+		if errors.Is(err, models.ErrCatNotFound) {
+			return exception.NewBadRequestError("user_cat_id_not_found").Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if issuerCat.UserId != userId {
+			return exception.NewNotFoundError("user_cat_not_owned")
+		}
+
+		receiverCat, err := findCat(r.Context(), tx, matchBody.MatchCatId)
+		// the private cats of others are not there to be matched
+		if err == nil && receiverCat.Private && receiverCat.UserId != userId {
+			err = models.ErrCatNotFound
+		}
+		if errors.Is(err, models.ErrCatNotFound) {
+			return exception.NewBadRequestError("match_cat_id_not_found").Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if issuerCat.Sex == receiverCat.Sex {
+			return exception.NewBadRequestError("match_same_sex")
+		}
+
+		if issuerCat.UserId == receiverCat.UserId {
+			return exception.NewBadRequestError("match_same_owner")
+		}
+
+		exist, err := tx.Matches().CrossCheckMatchCatId(r.Context(), issuerCat.Id, receiverCat.Id)
+		if err != nil {
+			return err
+		}
+		if exist != 0 {
+			return exception.NewBadRequestError("match_already_submitted")
+		}
+
+		exist, err = tx.Matches().CrossCheckMatchCatId(r.Context(), receiverCat.Id, issuerCat.Id)
+		if err != nil {
+			return err
+		}
+		if exist != 0 {
+			return exception.NewBadRequestError("match_already_submitted")
+		}
+
+		matchBody.UserCatId, matchBody.MatchCatId = issuerCat.Id, receiverCat.Id
+		id, createdAt, err = tx.Matches().NewMatch(r.Context(), matchBody)
+		return err
+	})
 	if err != nil {
-		panic(exception.NewBadRequestError("match cat id not found"))
+		return err
 	}
 
-	if issuerCat.Sex == receiverCat.Sex {
-		panic(exception.NewBadRequestError("gender cannot same"))
-	}
-
-	if issuerCat.UserEmail == receiverCat.UserEmail {
-		panic(exception.NewBadRequestError("cannot match the same owner"))
-	}
-
-	matchInsert := &models.Match{
-		IssuedBy: models.Issuer{
-			Email:     email,
-			Name:      username,
-			CreatedAt: time.Now(),
-		},
-		MatchUserEmail: receiverCat.UserEmail,
-		MatchCatDetail: models.CatDetail{
-			Id:          receiverCat.Id,
-			Name:        receiverCat.Name,
-			Race:        receiverCat.Race,
-			Sex:         receiverCat.Sex,
-			Description: receiverCat.Description,
-			AgeInMonth:  receiverCat.AgeInMonth,
-			ImageUrls:   receiverCat.ImageUrls,
-			HasMatched:  receiverCat.HasMatched,
-			CreatedAt:   receiverCat.CreatedAt,
-		},
-		UserCatDetail: models.CatDetail{
-			Id:          issuerCat.Id,
-			Name:        issuerCat.Name,
-			Race:        issuerCat.Race,
-			Sex:         issuerCat.Sex,
-			Description: issuerCat.Description,
-			AgeInMonth:  issuerCat.AgeInMonth,
-			ImageUrls:   issuerCat.ImageUrls,
-			HasMatched:  issuerCat.HasMatched,
-			CreatedAt:   issuerCat.CreatedAt,
-		},
-		Message: matchBody.Message,
-	}
-
-	exist := models.CrossCheckMatchCatId(r.Context(), tx, issuerCat.Id, receiverCat.Id)
-	if exist != 0 {
-		panic(exception.NewBadRequestError("Cat id already submit to match"))
-	}
-
-	exist = models.CrossCheckMatchCatId(r.Context(), tx, receiverCat.Id, issuerCat.Id)
-	if exist != 0 {
-		panic(exception.NewBadRequestError("Cat id already submit to match"))
-	}
-
-	id, err := models.NewMatch(r.Context(), tx, *matchInsert)
-
 	wrapper := &helper.WebResponse{
 		Message: "success",
 		Data: map[string]string{
 			"matchId":   id,
-			"createdAt": matchInsert.IssuedBy.CreatedAt.Format(time.RFC3339),
+			"createdAt": createdAt.Format(time.RFC3339),
 		},
 	}
 
 	helper.WriteToResponseBody(w, *wrapper, http.StatusCreated)
+	return nil
 }
 
-func GetMyMatch(w http.ResponseWriter, r *http.Request) {
-	email := r.Header.Get("email")
+func GetMyMatch(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	userId := principal.Id
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
-
-	matches, err := models.GetAllMatch(r.Context(), tx, email)
-	helper.PanicIfError(err)
+	var matches []models.Match
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) (err error) {
+		matches, err = tx.Matches().GetAllMatch(r.Context(), userId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
 	wrapper := &helper.WebResponse{
 		Message: "success",
@@ -126,90 +123,147 @@ func GetMyMatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
 }
 
-func ApproveMatch(w http.ResponseWriter, r *http.Request) {
-	email := r.Header.Get("email")
-	var bodyRequest models.ApproveRemoveRequest
+// receivedMatch returns the pending match matchId received by userId.
+func receivedMatch(r *http.Request, tx models.Tx, matchId string, userId string) (models.Match, error) {
+	match, err := tx.Matches().GetMatchById(r.Context(), matchId)
+	if err != nil {
+		return match, err
+	}
 
-	json.NewDecoder(r.Body).Decode(&bodyRequest)
+	// check valid user, if the user is not valid receiver match
+	if userId != match.MatchUserId {
+		return match, models.ErrMatchNotFound
+	}
 
-	err := validate.Struct(bodyRequest)
-	helper.PanicIfError(err)
+	if match.Status != "pending" {
+		return match, models.ErrMatchNotPending
+	}
 
-	matchId := bodyRequest.MatchId
+	return match, nil
+}
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
+// matchError translates the errors of the match owner checks.
+func matchError(err error) error {
+	if errors.Is(err, models.ErrMatchNotFound) {
+		return exception.NewNotFoundError("match_id_not_found").Wrap(err)
+	}
 
-	match, err := models.GetMatchById(r.Context(), tx, matchId)
-	if err != nil {
-		panic(exception.NewNotFoundError("match id not found"))
+	if errors.Is(err, models.ErrMatchNotPending) {
+		return exception.NewBadRequestError("match_id_not_valid").Wrap(err)
 	}
 
-	// check valid email, if the user is not valid receiver match
-	if email != match.MatchUserEmail {
-		panic(exception.NewNotFoundError("match id not found"))
+	return err
+}
+
+func ApproveMatch(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	userId := principal.Id
+	var bodyRequest models.ApproveRemoveRequest
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
 	}
 
-	if match.Status != "pending" {
-		panic(exception.NewBadRequestError("match id is no longer valid"))
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
 	}
 
-	models.ApproveMatch(r.Context(), tx, matchId)
-	models.RejectOtherMatch(r.Context(), tx, match.MatchCatDetail.Id, matchId)
-	models.RejectOtherMatch(r.Context(), tx, match.UserCatDetail.Id, matchId)
+	matchId := bodyRequest.MatchId
 
-	models.UpdateStatusCat(r.Context(), tx, match.MatchCatDetail.Id, match.UserCatDetail.Id)
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		match, err := receivedMatch(r, tx, matchId, userId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Matches().ApproveMatch(r.Context(), matchId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Matches().RejectOtherMatch(r.Context(), match.MatchCatDetail.Id, matchId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Matches().RejectOtherMatch(r.Context(), match.UserCatDetail.Id, matchId)
+		if err != nil {
+			return err
+		}
+
+		return tx.Cats().UpdateStatusCat(r.Context(), match.MatchCatDetail.Id, match.UserCatDetail.Id)
+	})
+	if err != nil {
+		return matchError(err)
+	}
 
 	helper.WriteToResponseBody(w, nil, http.StatusOK)
+	return nil
 }
 
-func RejectMatch(w http.ResponseWriter, r *http.Request) {
-	email := r.Header.Get("email")
-	matchId := r.PathValue("id")
-
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
+func RejectMatch(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	userId := principal.Id
+	var bodyRequest models.ApproveRemoveRequest
 
-	match, err := models.GetMatchById(r.Context(), tx, matchId)
+	err := helper.ParsingBody(w, r, &bodyRequest)
 	if err != nil {
-		panic(exception.NewNotFoundError("match id not found"))
+		return err
 	}
 
-	// check valid email, if the user is not valid receiver match
-	if email != match.MatchUserEmail {
-		panic(exception.NewNotFoundError("match id not found"))
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
 	}
 
-	if match.Status != "pending" {
-		panic(exception.NewBadRequestError("match id is no longer valid"))
-	}
+	matchId := bodyRequest.MatchId
 
-	models.RejectMatch(r.Context(), tx, matchId)
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		_, err := receivedMatch(r, tx, matchId, userId)
+		if err != nil {
+			return err
+		}
+
+		return tx.Matches().RejectMatch(r.Context(), matchId)
+	})
+	if err != nil {
+		return matchError(err)
+	}
 
 	helper.WriteToResponseBody(w, nil, http.StatusOK)
+	return nil
 }
 
-func DeleteMatch(w http.ResponseWriter, r *http.Request) {
+func DeleteMatch(w http.ResponseWriter, r *http.Request) error {
 	id := r.PathValue("id")
-	issuerEmail := r.Header.Get("email")
+	principal, _ := auth.FromContext(r.Context())
+	userId := principal.Id
 
-	tx := models.StartTx()
-	defer helper.CommitOrRollback(tx)
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		status, issuerId, err := tx.Matches().DeleteMatch(r.Context(), id)
+		if err != nil {
+			return err
+		}
 
-	status, email, err := models.DeleteMatch(r.Context(), tx, id)
-	if err != nil {
-		panic(exception.NewNotFoundError("match id not found"))
-	}
+		if issuerId != userId {
+			return exception.NewBadRequestError("match_not_issuer")
+		}
 
-	if email != issuerEmail {
-		panic(exception.NewBadRequestError("You not issuer"))
-	}
+		if status != "pending" {
+			return exception.NewBadRequestError("match_already_processed")
+		}
 
-	if status != "pending" {
-		panic(exception.NewBadRequestError("match is already approved / reject"))
+		return nil
+	})
+	if err != nil {
+		return matchError(err)
 	}
 
 	helper.WriteToResponseBody(w, nil, http.StatusOK)
+	return nil
 }
@@ -0,0 +1,460 @@
+package httpmux
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod        = 30
+	recoveryCodeCount = 10
+)
+
+var totpOptions = totp.ValidateOpts{
+	Period:    totpPeriod,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// newMFAChallenge replaces the pending MFA challenge of user, a token
+// proving the password was checked which LoginMFA trades for the tokens of
+// a session.
+func newMFAChallenge(ctx context.Context, tx models.Tx, user models.User) (string, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.UserTokens().DeleteUserTokens(ctx, user.Id, models.PurposeMFAChallenge)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.UserTokens().NewUserToken(ctx, models.UserToken{
+		UserId:    user.Id,
+		Email:     user.Email,
+		Purpose:   models.PurposeMFAChallenge,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(config.Env.MFA_CHALLENGE_TTL),
+	})
+
+	return token, err
+}
+
+// verifyTOTP returns the time step of code when it is valid for secret,
+// allowing one step of clock drift either way.
+func verifyTOTP(secret string, code string) (int64, bool) {
+	now := time.Now()
+
+	for _, drift := range []int64{0, -1, 1} {
+		at := now.Add(time.Duration(drift*totpPeriod) * time.Second)
+
+		expected, err := totp.GenerateCodeCustom(secret, at, totpOptions)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+
+	return 0, false
+}
+
+// checkSecondFactor accepts a TOTP code, each at most once, or an unused
+// recovery code of user.
+func checkSecondFactor(ctx context.Context, tx models.Tx, user models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := verifyTOTP(user.TOTPSecret, code); ok {
+		err := tx.Users().UseTOTPStep(ctx, user.Id, step)
+		if errors.Is(err, models.ErrTokenReused) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	err := tx.RecoveryCodes().UseRecoveryCode(ctx, user.Id, hashRecoveryCode(code))
+	if errors.Is(err, models.ErrTokenNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// newRecoveryCodes replaces the recovery codes of the user, the codes are only
+// shown once and only their hashes are stored.
+func newRecoveryCodes(ctx context.Context, tx models.Tx, userId string) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 10)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+
+		code := base32.StdEncoding.EncodeToString(b)[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, tx.RecoveryCodes().ReplaceRecoveryCodes(ctx, userId, hashes)
+}
+
+// hashRecoveryCode ignores case, spaces and dashes of code, the way users type it.
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(code)
+}
+
+// EnrollTOTP starts a TOTP enrollment, to be confirmed with a first code by
+// ConfirmTOTP. A new enrollment replaces an unconfirmed one.
+func EnrollTOTP(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+
+	var key *otp.Key
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), principal.Id)
+		if err != nil {
+			return err
+		}
+
+		if user.TOTPEnabledAt != nil {
+			return exception.NewConflictError("mfa_already_enabled")
+		}
+
+		key, err = totp.Generate(totp.GenerateOpts{
+			Issuer:      config.Env.MFA_ISSUER,
+			AccountName: user.Email,
+			Period:      totpPeriod,
+			Digits:      totpOptions.Digits,
+			Algorithm:   totpOptions.Algorithm,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Users().SetTOTPSecret(r.Context(), user.Id, key.Secret())
+	})
+	if err != nil {
+		return err
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return err
+	}
+
+	var qrCode bytes.Buffer
+	err = png.Encode(&qrCode, img)
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "Scan the QR code, then confirm with a first code",
+		Data: map[string]string{
+			"secret":     key.Secret(),
+			"otpauthUri": key.URL(),
+			"qrCode":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode.Bytes()),
+		},
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusCreated)
+	return nil
+}
+
+// ConfirmTOTP turns TOTP on with a first code of the enrolled secret and
+// returns the recovery codes.
+func ConfirmTOTP(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	bodyRequest := MFACodeRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	var codes []string
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), principal.Id)
+		if err != nil {
+			return err
+		}
+
+		if user.TOTPEnabledAt != nil {
+			return exception.NewConflictError("mfa_already_enabled")
+		}
+
+		if user.TOTPSecret == "" {
+			return exception.NewBadRequestError("mfa_not_enrolled")
+		}
+
+		step, ok := verifyTOTP(user.TOTPSecret, strings.TrimSpace(bodyRequest.Code))
+		if !ok {
+			return exception.NewBadRequestError("mfa_code_invalid")
+		}
+
+		err = tx.Users().UseTOTPStep(r.Context(), user.Id, step)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Users().EnableTOTP(r.Context(), user.Id)
+		if err != nil {
+			return err
+		}
+
+		codes, err = newRecoveryCodes(r.Context(), tx, user.Id)
+		if err != nil {
+			return err
+		}
+
+		return audit(r.Context(), tx, "user.mfa_enable", "user", user.Id, "email "+user.Email)
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "Two-factor authentication enabled, keep the recovery codes somewhere safe",
+		Data:    map[string][]string{"recoveryCodes": codes},
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// DisableTOTP turns TOTP off, proven with a code or a recovery code.
+func DisableTOTP(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	bodyRequest := MFACodeRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), principal.Id)
+		if err != nil {
+			return err
+		}
+
+		if user.TOTPEnabledAt == nil {
+			return exception.NewBadRequestError("mfa_not_enabled")
+		}
+
+		ok, err := checkSecondFactor(r.Context(), tx, user, bodyRequest.Code)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return exception.NewBadRequestError("mfa_code_invalid")
+		}
+
+		err = tx.Users().SetTOTPSecret(r.Context(), user.Id, "")
+		if err != nil {
+			return err
+		}
+
+		err = tx.RecoveryCodes().ReplaceRecoveryCodes(r.Context(), user.Id, nil)
+		if err != nil {
+			return err
+		}
+
+		return audit(r.Context(), tx, "user.mfa_disable", "user", user.Id, "email "+user.Email)
+	})
+	if err != nil {
+		return err
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "Two-factor authentication disabled"}, http.StatusOK)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, proven with a code
+// or a recovery code.
+func RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	bodyRequest := MFACodeRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	var codes []string
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), principal.Id)
+		if err != nil {
+			return err
+		}
+
+		if user.TOTPEnabledAt == nil {
+			return exception.NewBadRequestError("mfa_not_enabled")
+		}
+
+		ok, err := checkSecondFactor(r.Context(), tx, user, bodyRequest.Code)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return exception.NewBadRequestError("mfa_code_invalid")
+		}
+
+		codes, err = newRecoveryCodes(r.Context(), tx, user.Id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "Recovery codes replaced",
+		Data:    map[string][]string{"recoveryCodes": codes},
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// LoginMFA ends a login of a user with TOTP on, trading the MFA challenge
+// and a code or a recovery code for the tokens of a new session. Wrong codes
+// count as failed logins.
+func LoginMFA(w http.ResponseWriter, r *http.Request) error {
+	bodyRequest := MFALoginRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	ip := helper.ClientIP(r)
+	invalidToken := exception.NewBadRequestError("mfa_token_invalid")
+
+	var user models.User
+	var tokens tokenPair
+	var codeErr error
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		challenge, err := tx.UserTokens().GetUserTokenByHash(r.Context(), models.PurposeMFAChallenge, hashToken(bodyRequest.MFAToken))
+		if errors.Is(err, models.ErrTokenNotFound) {
+			return invalidToken.Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) {
+			return invalidToken
+		}
+
+		attempts, err := loginAttempts(r.Context(), tx, challenge.Email, ip)
+		if err != nil {
+			return err
+		}
+
+		err = checkLoginAllowed(w, attempts)
+		if err != nil {
+			return err
+		}
+
+		user, err = tx.Users().GetUserById(r.Context(), challenge.UserId)
+		if err != nil {
+			return err
+		}
+
+		if user.DisabledAt != nil {
+			return exception.NewForbiddenError("account_disabled")
+		}
+
+		// TOTP was turned off since the password was checked
+		if user.TOTPEnabledAt == nil {
+			return invalidToken
+		}
+
+		ok, err := checkSecondFactor(r.Context(), tx, user, bodyRequest.Code)
+		if err != nil {
+			return err
+		}
+
+		// commit the failure so it is counted
+		if !ok {
+			codeErr = exception.NewBadRequestError("mfa_code_invalid")
+			return recordLoginFailure(r.Context(), tx, user.Email, ip)
+		}
+
+		err = tx.UserTokens().UseUserToken(r.Context(), challenge.Id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.LoginAttempts().ResetLoginAttempt(r.Context(), models.AttemptScopeAccount, strings.ToLower(user.Email))
+		if err != nil {
+			return err
+		}
+
+		tokens, err = issueTokens(r, tx, user, "")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if codeErr != nil {
+		return codeErr
+	}
+
+	writeLoggedIn(w, user, tokens)
+	return nil
+}
@@ -0,0 +1,69 @@
+package httpmux
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestVerifyTOTP(t *testing.T) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "Cats Social", AccountName: "totp@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := totp.Generate(totp.GenerateOpts{Issuer: "Cats Social", AccountName: "other@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	code := func(secret string, steps int) string {
+		c, err := totp.GenerateCodeCustom(secret, now.Add(time.Duration(steps*totpPeriod)*time.Second), totpOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// the codes are relative to now, the results cannot be trusted once the
+	// clock moved to the next step
+	crossed := func() bool {
+		return time.Now().Unix()/totpPeriod != now.Unix()/totpPeriod
+	}
+
+	// one step of drift either way, reported as the step of the code
+	for _, steps := range []int{-1, 0, 1} {
+		step, ok := verifyTOTP(key.Secret(), code(key.Secret(), steps))
+		want := now.Unix()/totpPeriod + int64(steps)
+
+		if (!ok || step != want) && !crossed() {
+			t.Errorf("code %d steps away: got step %d, %t, want %d", steps, step, ok, want)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"two steps ago", key.Secret(), code(key.Secret(), -2)},
+		{"two steps ahead", key.Secret(), code(key.Secret(), 2)},
+		{"code of another secret", key.Secret(), code(other.Secret(), 0)},
+		{"not digits", key.Secret(), "abcdef"},
+		{"too short", key.Secret(), code(key.Secret(), 0)[:5]},
+		{"empty", key.Secret(), ""},
+		{"secret not base32", "not a secret!", "123456"},
+	}
+
+	for _, tt := range tests {
+		// a code of another secret matches by chance one time in a million
+		if tt.name == "code of another secret" && tt.code == code(tt.secret, 0) {
+			continue
+		}
+
+		if _, ok := verifyTOTP(tt.secret, tt.code); ok && !crossed() {
+			t.Errorf("%s: code %q accepted", tt.name, tt.code)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package httpmux
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+	"github.com/malikfajr/cats-social/password"
+)
+
+// PasswordChangeRequest and PasswordResetRequest carry the email and name of
+// their user for the password policy, see passwordPolicy.
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+	Email           string `json:"-"`
+	Name            string `json:"-"`
+}
+
+type PasswordForgotRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+	Email       string `json:"-"`
+	Name        string `json:"-"`
+}
+
+// ChangePassword replaces the password of the logged in user. Every other
+// session of the user is revoked, the current one stays logged in.
+func ChangePassword(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	bodyRequest := PasswordChangeRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	var user models.User
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err = tx.Users().GetUserById(r.Context(), principal.Id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// the token may predate a change of the email or name
+	bodyRequest.Email, bodyRequest.Name = user.Email, user.Name
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = confirmPassword(w, r, user.Id, bodyRequest.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	hash, err := password.Hash(bodyRequest.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		err := tx.Users().SetUserPassword(r.Context(), principal.Id, hash)
+		if err != nil {
+			return err
+		}
+
+		return tx.Sessions().RevokeUserSessions(r.Context(), principal.Id, principal.SessionId)
+	})
+	if err != nil {
+		return err
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "Password changed successfully"}, http.StatusOK)
+	return nil
+}
+
+// ForgotPassword mails a reset link to the user. The response is the same
+// whether the email is registered or not.
+func ForgotPassword(w http.ResponseWriter, r *http.Request) error {
+	bodyRequest := PasswordForgotRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserByEmail(r.Context(), bodyRequest.Email)
+		if err != nil {
+			return err
+		}
+
+		// only the latest link works
+		err = tx.UserTokens().DeleteUserTokens(r.Context(), user.Id, models.PurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.UserTokens().NewUserToken(r.Context(), models.UserToken{
+			UserId:    user.Id,
+			Email:     user.Email,
+			Purpose:   models.PurposePasswordReset,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(config.Env.PASSWORD_RESET_TTL),
+		})
+		return err
+	})
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		return err
+	}
+
+	if err == nil {
+		link := config.Env.APP_URL + "/reset-password?token=" + token
+		minutes := strconv.Itoa(int(config.Env.PASSWORD_RESET_TTL.Minutes()))
+		sendMail(r, bodyRequest.Email, "mail_password_reset", minutes, link)
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "If the email is registered, a password reset link has been sent",
+		Data:    nil,
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusAccepted)
+	return nil
+}
+
+// ResetPassword sets a new password with the token of a reset link and logs
+// the user out everywhere.
+func ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	bodyRequest := PasswordResetRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	invalidToken := exception.NewBadRequestError("reset_token_invalid")
+
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		token, err := tx.UserTokens().GetUserTokenByHash(r.Context(), models.PurposePasswordReset, hashToken(bodyRequest.Token))
+		if errors.Is(err, models.ErrTokenNotFound) {
+			return invalidToken.Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			return invalidToken
+		}
+
+		user, err := tx.Users().GetUserById(r.Context(), token.UserId)
+		if err != nil {
+			return err
+		}
+
+		// the password policy needs the user the token belongs to
+		bodyRequest.Email, bodyRequest.Name = user.Email, user.Name
+		err = validate.Struct(bodyRequest)
+		if err != nil {
+			return err
+		}
+
+		hash, err := password.Hash(bodyRequest.NewPassword)
+		if err != nil {
+			return err
+		}
+
+		err = tx.UserTokens().UseUserToken(r.Context(), token.Id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Users().SetUserPassword(r.Context(), user.Id, hash)
+		if err != nil {
+			return err
+		}
+
+		return tx.Sessions().RevokeUserSessions(r.Context(), user.Id, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "Password reset successfully"}, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,93 @@
+package httpmux
+
+import (
+	"net/http"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+)
+
+type sessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// GetSessions lists the sessions of the logged in user that are not revoked,
+// the one of the request is marked current.
+func GetSessions(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+
+	var sessions []models.Session
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) (err error) {
+		sessions, err = tx.Sessions().GetUserSessions(r.Context(), principal.Id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	data := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, sessionResponse{Session: session, Current: session.Id == principal.SessionId})
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    data,
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// RevokeSession logs one session of the logged in user out. Sessions of
+// other users are not found.
+func RevokeSession(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		session, err := tx.Sessions().GetSession(r.Context(), r.PathValue("id"))
+		if err != nil {
+			return err
+		}
+
+		if session.UserId != principal.Id || session.RevokedAt != nil {
+			return models.ErrSessionNotFound
+		}
+
+		return tx.Sessions().RevokeSession(r.Context(), session.Id)
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    nil,
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere, the current session
+// included.
+func RevokeAllSessions(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		return tx.Sessions().RevokeUserSessions(r.Context(), principal.Id, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    nil,
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,9 @@
+package httpmux
+
+import "github.com/malikfajr/cats-social/models"
+
+var store models.Store
+
+func InitStore(s models.Store) {
+	store = s
+}
@@ -0,0 +1,189 @@
+package httpmux
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// newOpaqueToken returns a random URL safe token, only its hash is stored.
+func newOpaqueToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens rotates the refresh token of sessionId, or starts a new
+// session from the device of r when it is empty, and signs an access token
+// bound to the session. Only the hash of the refresh token is stored.
+func issueTokens(r *http.Request, tx models.Tx, user models.User, sessionId string) (tokenPair, error) {
+	if sessionId == "" {
+		session, err := tx.Sessions().NewSession(r.Context(), models.Session{
+			UserId:    user.Id,
+			UserAgent: helper.UserAgent(r),
+			IP:        helper.ClientIP(r),
+		})
+		if err != nil {
+			return tokenPair{}, err
+		}
+
+		sessionId = session.Id
+	}
+
+	refreshToken, refreshTokenHash, err := newOpaqueToken()
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	_, err = tx.Tokens().NewRefreshToken(r.Context(), models.RefreshToken{
+		FamilyId:  sessionId,
+		UserId:    user.Id,
+		TokenHash: refreshTokenHash,
+		ExpiresAt: time.Now().Add(config.Env.REFRESH_TOKEN_TTL),
+	})
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	accessToken, err := auth.IssueToken(user, sessionId)
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	return tokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// RefreshTokenHandler exchanges a refresh token for a new token pair. A
+// refresh token is single use: replaying one revokes its whole family, since
+// either the client or an attacker holds a stolen copy.
+func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) error {
+	bodyRequest := RefreshRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	invalidToken := exception.NewUnauthorizedError("refresh_token_invalid")
+	reused := false
+
+	var tokens tokenPair
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		token, err := tx.Tokens().GetRefreshTokenByHash(r.Context(), hashToken(bodyRequest.RefreshToken))
+		if errors.Is(err, models.ErrTokenNotFound) {
+			return invalidToken.Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		session, err := tx.Sessions().GetSession(r.Context(), token.FamilyId)
+		if err != nil {
+			return err
+		}
+
+		if session.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+			return invalidToken
+		}
+
+		err = tx.Tokens().UseRefreshToken(r.Context(), token.Id)
+		if errors.Is(err, models.ErrTokenReused) {
+			// the revocation has to be committed, the error is returned afterwards
+			reused = true
+			return tx.Sessions().RevokeSession(r.Context(), session.Id)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Sessions().TouchSession(r.Context(), session.Id, helper.ClientIP(r), helper.UserAgent(r))
+		if err != nil {
+			return err
+		}
+
+		user, err := tx.Users().GetUserById(r.Context(), token.UserId)
+		if err != nil {
+			return err
+		}
+
+		if user.DisabledAt != nil {
+			return invalidToken
+		}
+
+		tokens, err = issueTokens(r, tx, user, session.Id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if reused {
+		return invalidToken.Wrap(models.ErrTokenReused)
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "Token refreshed successfully",
+		Data: map[string]string{
+			"accessToken":  tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+		},
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// LogoutHandler revokes the session of the access token, its refresh token
+// and every access token bound to it stop working.
+func LogoutHandler(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		return tx.Sessions().RevokeSession(r.Context(), principal.SessionId)
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    nil,
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
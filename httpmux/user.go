@@ -0,0 +1,274 @@
+package httpmux
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+)
+
+// ProfileRequest changes only the fields present in the body, an empty bio,
+// avatar URL or city clears it.
+type ProfileRequest struct {
+	Name      *string `json:"name" validate:"omitnil,min=5,max=50"`
+	Bio       *string `json:"bio" validate:"omitnil,max=160"`
+	AvatarUrl *string `json:"avatarUrl" validate:"omitnil,url,max=255"`
+	City      *string `json:"city" validate:"omitnil,max=50"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+	// Code is a TOTP or recovery code, required once TOTP is enabled.
+	Code string `json:"code"`
+}
+
+type profile struct {
+	Id            string `json:"id"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	Bio           string `json:"bio"`
+	AvatarUrl     string `json:"avatarUrl"`
+	City          string `json:"city"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"emailVerified"`
+	MFAEnabled    bool   `json:"mfaEnabled"`
+}
+
+type publicProfile struct {
+	Id        string       `json:"id"`
+	Name      string       `json:"name"`
+	Bio       string       `json:"bio"`
+	AvatarUrl string       `json:"avatarUrl"`
+	City      string       `json:"city"`
+	Cats      []models.Cat `json:"cats"`
+}
+
+func newProfile(user models.User) profile {
+	return profile{
+		Id:            user.Id,
+		Email:         user.Email,
+		Name:          user.Name,
+		Bio:           user.Bio,
+		AvatarUrl:     user.AvatarUrl,
+		City:          user.City,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		MFAEnabled:    user.TOTPEnabledAt != nil,
+	}
+}
+
+func GetMe(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+
+	var user models.User
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) (err error) {
+		user, err = tx.Users().GetUserById(r.Context(), principal.Id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    newProfile(user),
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// UpdateMe changes the profile of the logged in user. A new name shows in
+// access tokens from the next refresh on.
+func UpdateMe(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	bodyRequest := ProfileRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	// an empty avatar URL is no URL to validate
+	clearAvatar := bodyRequest.AvatarUrl != nil && *bodyRequest.AvatarUrl == ""
+	if clearAvatar {
+		bodyRequest.AvatarUrl = nil
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	var user models.User
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err = tx.Users().GetUserById(r.Context(), principal.Id)
+		if err != nil {
+			return err
+		}
+
+		if bodyRequest.Name != nil {
+			user.Name = *bodyRequest.Name
+		}
+		if bodyRequest.Bio != nil {
+			user.Bio = *bodyRequest.Bio
+		}
+		if bodyRequest.AvatarUrl != nil {
+			user.AvatarUrl = *bodyRequest.AvatarUrl
+		}
+		if clearAvatar {
+			user.AvatarUrl = ""
+		}
+		if bodyRequest.City != nil {
+			user.City = *bodyRequest.City
+		}
+
+		return tx.Users().SetUserProfile(r.Context(), user)
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    newProfile(user),
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// GetUser is the public profile of a user with its non-private cats, without
+// the email. Disabled users are not found.
+func GetUser(w http.ResponseWriter, r *http.Request) error {
+	var data publicProfile
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), r.PathValue("id"))
+		if err != nil {
+			return err
+		}
+
+		if user.DisabledAt != nil {
+			return models.ErrUserNotFound
+		}
+
+		page, err := tx.Cats().GetAllCat(r.Context(), models.CatParam{
+			Owned:   "true",
+			UserId:  user.Id,
+			Limit:   r.URL.Query().Get("limit"),
+			Offsset: r.URL.Query().Get("offset"),
+		})
+		if err != nil {
+			return err
+		}
+
+		data = publicProfile{
+			Id:        user.Id,
+			Name:      user.Name,
+			Bio:       user.Bio,
+			AvatarUrl: user.AvatarUrl,
+			City:      user.City,
+			Cats:      page.Cats,
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	wrapper := helper.WebResponse{
+		Message: "success",
+		Data:    data,
+	}
+
+	helper.WriteToResponseBody(w, wrapper, http.StatusOK)
+	return nil
+}
+
+// DeleteMe deletes the account of the logged in user after checking its
+// password, and its second factor when enabled. Its pending matches are
+// cancelled and its cats deleted, but for those in an approved match.
+func DeleteMe(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+	bodyRequest := DeleteAccountRequest{}
+
+	err := helper.ParsingBody(w, r, &bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = validate.Struct(bodyRequest)
+	if err != nil {
+		return err
+	}
+
+	err = confirmPassword(w, r, principal.Id, bodyRequest.Password)
+	if err != nil {
+		return err
+	}
+
+	var codeErr error
+	err = models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserById(r.Context(), principal.Id)
+		if err != nil {
+			return err
+		}
+
+		if user.TOTPEnabledAt != nil {
+			ok, err := checkSecondFactor(r.Context(), tx, user, bodyRequest.Code)
+			if err != nil {
+				return err
+			}
+
+			// commit the failure so it is counted
+			if !ok {
+				codeErr = exception.NewBadRequestError("mfa_code_invalid")
+				return recordLoginFailure(r.Context(), tx, user.Email, helper.ClientIP(r))
+			}
+		}
+
+		matches, err := tx.Matches().CancelUserMatches(r.Context(), user.Id)
+		if err != nil {
+			return err
+		}
+
+		cats, err := tx.Cats().DestroyUserCats(r.Context(), user.Id)
+		if err != nil {
+			return err
+		}
+
+		// the cats in an approved match stay, without an owner, so the other
+		// side of the match keeps it
+		kept, err := tx.Cats().DetachUserCats(r.Context(), user.Id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Matches().DetachUserMatches(r.Context(), user.Id)
+		if err != nil {
+			return err
+		}
+
+		// recorded while the user, its actor, still exists
+		err = audit(r.Context(), tx, "user.delete", "user", user.Id, fmt.Sprintf("email %s, %d cats deleted, %d kept for their matches, %d pending matches cancelled",
+			user.Email, cats, kept, matches))
+		if err != nil {
+			return err
+		}
+
+		return tx.Users().DeleteUser(r.Context(), user.Id)
+	})
+	if err != nil {
+		return err
+	}
+
+	if codeErr != nil {
+		return codeErr
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "Account deleted successfully"}, http.StatusOK)
+	return nil
+}
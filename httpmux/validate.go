@@ -5,6 +5,9 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/cats-social/i18n"
+	"github.com/malikfajr/cats-social/models"
+	"github.com/malikfajr/cats-social/password"
 )
 
 var validate *validator.Validate
@@ -20,4 +23,43 @@ func InitValidator() {
 		}
 		return name
 	})
+
+	err := i18n.RegisterValidator(validate)
+	if err != nil {
+		panic(err)
+	}
+
+	validate.RegisterStructValidation(passwordPolicy, models.User{}, PasswordChangeRequest{}, PasswordResetRequest{})
+	err = i18n.RegisterRules(validate, password.Problems...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// passwordPolicy reports every problem of the password set by a request,
+// checked against the email and name of its user.
+func passwordPolicy(sl validator.StructLevel) {
+	var field, structField, newPassword string
+	var personal []string
+
+	switch request := sl.Current().Interface().(type) {
+	case models.User:
+		field, structField, newPassword = "password", "Password", request.Password
+		personal = []string{request.Email, request.Name}
+	case PasswordChangeRequest:
+		field, structField, newPassword = "newPassword", "NewPassword", request.NewPassword
+		personal = []string{request.Email, request.Name}
+	case PasswordResetRequest:
+		field, structField, newPassword = "newPassword", "NewPassword", request.NewPassword
+		personal = []string{request.Email, request.Name}
+	}
+
+	// a missing password is already reported by required
+	if newPassword == "" {
+		return
+	}
+
+	for _, problem := range password.Check(newPassword, personal...) {
+		sl.ReportError(newPassword, field, structField, problem.Rule, problem.Param)
+	}
 }
@@ -0,0 +1,153 @@
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/exception"
+	"github.com/malikfajr/cats-social/helper"
+	"github.com/malikfajr/cats-social/models"
+)
+
+// newVerificationToken replaces the pending verification token of the user
+// for its current email, the token must be mailed with sendVerificationMail
+// once tx is committed.
+func newVerificationToken(ctx context.Context, tx models.Tx, user models.User) (string, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.UserTokens().DeleteUserTokens(ctx, user.Id, models.PurposeVerifyEmail)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.UserTokens().NewUserToken(ctx, models.UserToken{
+		UserId:    user.Id,
+		Email:     user.Email,
+		Purpose:   models.PurposeVerifyEmail,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(config.Env.EMAIL_VERIFICATION_TTL),
+	})
+
+	return token, err
+}
+
+func sendVerificationMail(r *http.Request, email string, token string) {
+	link := config.Env.API_URL + "/v1/user/verify?token=" + token
+	hours := strconv.Itoa(int(math.Ceil(config.Env.EMAIL_VERIFICATION_TTL.Hours())))
+	sendMail(r, email, "mail_verify_email", hours, link)
+}
+
+// requireVerifiedEmail rejects action of an unverified user when the policy
+// EMAIL_VERIFICATION_REQUIRED names the action.
+func requireVerifiedEmail(ctx context.Context, tx models.Tx, userId string, action string) error {
+	if !config.EmailVerificationRequired(action) {
+		return nil
+	}
+
+	user, err := tx.Users().GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		return exception.NewForbiddenError("email_not_verified")
+	}
+
+	return nil
+}
+
+// VerifyEmail is the target of the link mailed on registration.
+func VerifyEmail(w http.ResponseWriter, r *http.Request) error {
+	invalidToken := exception.NewBadRequestError("verification_token_invalid")
+
+	tokenString := r.URL.Query().Get("token")
+	if tokenString == "" {
+		return invalidToken
+	}
+
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) error {
+		token, err := tx.UserTokens().GetUserTokenByHash(r.Context(), models.PurposeVerifyEmail, hashToken(tokenString))
+		if errors.Is(err, models.ErrTokenNotFound) {
+			return invalidToken.Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			return invalidToken
+		}
+
+		user, err := tx.Users().GetUserById(r.Context(), token.UserId)
+		if err != nil {
+			return err
+		}
+
+		// the link verifies the address it was mailed to only
+		if user.Email != token.Email {
+			return invalidToken
+		}
+
+		err = tx.UserTokens().UseUserToken(r.Context(), token.Id)
+		if err != nil {
+			return err
+		}
+
+		return tx.Users().SetEmailVerified(r.Context(), user.Id)
+	})
+	if err != nil {
+		return err
+	}
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "Email verified successfully"}, http.StatusOK)
+	return nil
+}
+
+// ResendVerification mails a new verification link, at most once every
+// EMAIL_VERIFICATION_RESEND_INTERVAL.
+func ResendVerification(w http.ResponseWriter, r *http.Request) error {
+	principal, _ := auth.FromContext(r.Context())
+
+	var token string
+	var user models.User
+	err := models.WithTx(r.Context(), store, func(tx models.Tx) (err error) {
+		user, err = tx.Users().GetUserById(r.Context(), principal.Id)
+		if err != nil {
+			return err
+		}
+
+		if user.EmailVerifiedAt != nil {
+			return exception.NewConflictError("email_already_verified")
+		}
+
+		latest, err := tx.UserTokens().GetLatestUserToken(r.Context(), user.Id, models.PurposeVerifyEmail)
+		if err != nil && !errors.Is(err, models.ErrTokenNotFound) {
+			return err
+		}
+
+		if wait := config.Env.EMAIL_VERIFICATION_RESEND_INTERVAL - time.Since(latest.CreatedAt); err == nil && wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return exception.NewTooManyRequestsError("verification_resend_throttled")
+		}
+
+		token, err = newVerificationToken(r.Context(), tx, user)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	sendVerificationMail(r, user.Email, token)
+
+	helper.WriteToResponseBody(w, helper.WebResponse{Message: "Verification email sent"}, http.StatusAccepted)
+	return nil
+}
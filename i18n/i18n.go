@@ -0,0 +1,154 @@
+// Package i18n serves the response messages of the API in the language
+// asked by the client through Accept-Language.
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	id_translations "github.com/go-playground/validator/v10/translations/id"
+)
+
+// DefaultLocale is the last step of every fallback chain.
+const DefaultLocale = "en"
+
+// catalogs holds the messages of every supported locale by key.
+var catalogs = map[string]map[string]string{
+	"en": enMessages,
+	"id": idMessages,
+}
+
+var universal = ut.New(en.New(), en.New(), id.New())
+
+func init() {
+	for locale, catalog := range catalogs {
+		trans, _ := universal.GetTranslator(locale)
+
+		for key, text := range catalog {
+			err := trans.Add(key, text, false)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// RegisterValidator registers the validation messages of every locale on v.
+func RegisterValidator(v *validator.Validate) error {
+	trans, _ := universal.GetTranslator("en")
+	err := en_translations.RegisterDefaultTranslations(v, trans)
+	if err != nil {
+		return err
+	}
+
+	trans, _ = universal.GetTranslator("id")
+	return id_translations.RegisterDefaultTranslations(v, trans)
+}
+
+// RegisterRules registers the messages of custom validation rules on v. The
+// message of a rule is the catalog message of the same key, with the field
+// as {0} and the parameter of the rule as {1}.
+func RegisterRules(v *validator.Validate, rules ...string) error {
+	for locale := range catalogs {
+		for _, rule := range rules {
+			err := v.RegisterTranslation(rule, Translator(locale), func(ut.Translator) error {
+				// the message is already added from the catalog
+				return nil
+			}, func(trans ut.Translator, fe validator.FieldError) string {
+				message, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+				if err != nil {
+					return fe.Error()
+				}
+				return message
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Translator returns the translator of locale, it must be a supported locale.
+func Translator(locale string) ut.Translator {
+	trans, _ := universal.GetTranslator(locale)
+	return trans
+}
+
+// T returns the message key in locale, falling back to the default locale
+// and at last to the key itself. Params replace the {0}, {1}... placeholders.
+func T(locale string, key string, params ...string) string {
+	for _, l := range []string{locale, DefaultLocale} {
+		if _, ok := catalogs[l][key]; !ok {
+			continue
+		}
+
+		message, err := Translator(l).T(key, params...)
+		if err == nil {
+			return message
+		}
+	}
+
+	return key
+}
+
+// FromRequest returns the locale picked from the Accept-Language header of r.
+func FromRequest(r *http.Request) string {
+	return Match(r.Header.Get("Accept-Language"))
+}
+
+// Match returns the supported locale preferred by an Accept-Language value.
+// A region is dropped when only its language is supported, so id-ID falls
+// back to id, and DefaultLocale is used when nothing matches.
+func Match(acceptLanguage string) string {
+	type tag struct {
+		name string
+		q    float64
+	}
+
+	tags := []tag{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		tags = append(tags, tag{name: strings.ToLower(name), q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		if t.q <= 0 {
+			continue
+		}
+
+		language, _, _ := strings.Cut(strings.ReplaceAll(t.name, "_", "-"), "-")
+		for _, candidate := range []string{t.name, language} {
+			if _, ok := catalogs[candidate]; ok {
+				return candidate
+			}
+		}
+	}
+
+	return DefaultLocale
+}
@@ -0,0 +1,129 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestCatalogsComplete(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for key := range catalogs[DefaultLocale] {
+			if strings.TrimSpace(catalog[key]) == "" {
+				t.Errorf("catalog %s: missing message %q", locale, key)
+			}
+		}
+
+		for key := range catalog {
+			if _, ok := catalogs[DefaultLocale][key]; !ok {
+				t.Errorf("catalog %s: message %q is not in the %s catalog", locale, key, DefaultLocale)
+			}
+
+			// universal-translator panics on placeholders out of order, e.g. {1} before {0}
+			func() {
+				defer func() {
+					if p := recover(); p != nil {
+						t.Errorf("catalog %s: message %q cannot be rendered: %v", locale, key, p)
+					}
+				}()
+				Translator(locale).T(key, "a", "b", "c")
+			}()
+		}
+	}
+}
+
+func TestValidatorTranslationsComplete(t *testing.T) {
+	// every rule used by the request structs
+	type request struct {
+		Required string   `validate:"required"`
+		Email    string   `validate:"email"`
+		Min      string   `validate:"min=5"`
+		Max      string   `validate:"max=1"`
+		OneOf    string   `validate:"oneof=male female"`
+		Urls     []string `validate:"dive,url"`
+	}
+
+	v := validator.New()
+	err := RegisterValidator(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.Struct(request{Email: "x", Min: "x", Max: "xx", OneOf: "x", Urls: []string{"x"}})
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok || len(validationErrors) != 6 {
+		t.Fatalf("expected 6 validation errors, got %v", err)
+	}
+
+	for locale := range catalogs {
+		for _, e := range validationErrors {
+			message := e.Translate(Translator(locale))
+			if message == e.Error() {
+				t.Errorf("catalog %s: rule %q has no translation", locale, e.Tag())
+			}
+		}
+	}
+}
+
+func TestRulesTranslated(t *testing.T) {
+	type request struct {
+		Password string `validate:"password_too_short=8"`
+	}
+
+	v := validator.New()
+	v.RegisterValidation("password_too_short", func(validator.FieldLevel) bool { return false })
+
+	err := RegisterRules(v, "password_too_short")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.Struct(request{})
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok || len(validationErrors) != 1 {
+		t.Fatalf("expected 1 validation error, got %v", err)
+	}
+
+	for locale := range catalogs {
+		message := validationErrors[0].Translate(Translator(locale))
+		if !strings.Contains(message, "Password") || !strings.Contains(message, "8") {
+			t.Errorf("catalog %s: got %q", locale, message)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := map[string]string{
+		"":                          "en",
+		"id":                        "id",
+		"id-ID":                     "id",
+		"en-US,en;q=0.9":            "en",
+		"fr-FR, id;q=0.5, en;q=0.4": "id",
+		"en;q=0.3, id;q=0.8":        "id",
+		"id;q=0, en":                "en",
+		"de":                        DefaultLocale,
+		"ID_id":                     "id",
+		"id;q=invalid, en-GB;q=0.1": "en",
+	}
+
+	for acceptLanguage, want := range tests {
+		if got := Match(acceptLanguage); got != want {
+			t.Errorf("Match(%q) = %q, want %q", acceptLanguage, got, want)
+		}
+	}
+}
+
+func TestTFallback(t *testing.T) {
+	if got := T("id", "password_wrong"); got != idMessages["password_wrong"] {
+		t.Errorf("got %q", got)
+	}
+
+	if got := T("xx", "password_wrong"); got != enMessages["password_wrong"] {
+		t.Errorf("unknown locale: got %q", got)
+	}
+
+	if got := T("id", "no_such_key"); got != "no_such_key" {
+		t.Errorf("unknown key: got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package i18n
+
+var enMessages = map[string]string{
+	"bad_request":            "bad request",
+	"internal_server_error":  "Internal server error",
+	"request_canceled":       "Request canceled",
+	"request_timed_out":      "Request timed out",
+	"invalid_param":          "invalid parameter",
+	"invalid_body":           "invalid request body",
+	"request_body_too_large": "request body too large",
+	"unsupported_media_type": "unsupported media type",
+	"body_expected_json":     "expected application/json",
+	"body_single_value":      "body must hold a single JSON value",
+	"body_limit":             "limit is {0} bytes",
+	"body_empty":             "body is empty",
+	"body_incomplete":        "body is not complete JSON",
+	"body_malformed":         "malformed JSON at offset {0}",
+	"body_not_json":          "body is not valid JSON",
+	"body_field_type":        "field {0} must be {1}",
+	"body_unknown_field":     "unknown field {0}",
+
+	"user_not_found":        "user not found",
+	"email_taken":           "Email has taken",
+	"token_missing":         "authentication required",
+	"token_invalid":         "invalid token",
+	"refresh_token_invalid": "refresh token is invalid or expired",
+	"forbidden":             "you do not have permission to do this",
+	"account_disabled":      "account is disabled",
+	"admin_self_action":     "cannot change your own account",
+	"password_wrong":        "Password wrong",
+	"cat_not_found":         "cat not found",
+	"cat_id_not_found":      "id is not found",
+	"cat_sex_locked":        "Cannot update sex when cat requested to match",
+
+	"match_not_found":         "match not found",
+	"match_not_pending":       "match is no longer pending",
+	"match_id_not_found":      "match id not found",
+	"match_id_not_valid":      "match id is no longer valid",
+	"match_already_processed": "match is already approved / reject",
+	"match_not_issuer":        "You not issuer",
+	"user_cat_id_not_found":   "user cat id not found",
+	"match_cat_id_not_found":  "match cat id not found",
+	"user_cat_not_owned":      "userCatId is not belong to the user",
+	"match_same_sex":          "gender cannot same",
+	"match_same_owner":        "cannot match the same owner",
+	"match_already_submitted": "Cat id already submit to match",
+
+	"password_too_short": "{0} must be at least {1} characters long",
+	"password_too_long":  "{0} must be at most {1} characters long",
+	"password_weak":      "{0} is too easy to guess, make it longer or mix letters, digits and symbols",
+	"password_common":    "{0} is one of the most common passwords",
+	"password_personal":  "{0} must not contain your email or name",
+
+	"credentials_invalid": "email or password is wrong",
+	"login_throttled":     "too many failed logins, try again later",
+	"login_locked":        "login is locked after too many failed attempts, try again later",
+
+	"mfa_already_enabled": "two-factor authentication is already enabled",
+	"mfa_not_enrolled":    "start the two-factor authentication enrollment first",
+	"mfa_not_enabled":     "two-factor authentication is not enabled",
+	"mfa_code_invalid":    "the code is invalid",
+	"mfa_token_invalid":   "MFA token is invalid or expired, log in again",
+
+	"session_not_found": "session not found",
+
+	"reset_token_invalid": "reset token is invalid or expired",
+
+	"mail_password_reset_subject": "Reset your Cats Social password",
+	"mail_password_reset_body":    "Someone asked to reset the password of your Cats Social account.\n\nOpen this link to choose a new password, it expires in {0} minutes:\n{1}\n\nIf it was not you, ignore this email, your password is unchanged.",
+
+	"verification_token_invalid":    "verification token is invalid or expired",
+	"email_already_verified":        "email is already verified",
+	"verification_resend_throttled": "verification email was sent recently, try again later",
+	"email_not_verified":            "verify your email address first",
+
+	"email_unchanged":            "the new email is the current one",
+	"email_change_token_invalid": "email change token is invalid or expired",
+
+	"mail_email_change_subject":  "Confirm your new Cats Social email address",
+	"mail_email_change_body":     "Someone asked to change the email of a Cats Social account to this address.\n\nOpen this link to confirm the change, it expires in {0} hours:\n{1}\n\nIf it was not you, ignore this email.",
+	"mail_email_changed_subject": "Your Cats Social email address was changed",
+	"mail_email_changed_body":    "The email of your Cats Social account was changed to {0}.\n\nIf it was not you, contact us right away.",
+
+	"mail_verify_email_subject": "Verify your Cats Social email address",
+	"mail_verify_email_body":    "Welcome to Cats Social!\n\nOpen this link to verify your email address, it expires in {0} hours:\n{1}\n\nIf you did not create an account, ignore this email.",
+}
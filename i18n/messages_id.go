@@ -0,0 +1,86 @@
+package i18n
+
+var idMessages = map[string]string{
+	"bad_request":            "permintaan tidak valid",
+	"internal_server_error":  "Terjadi kesalahan pada server",
+	"request_canceled":       "Permintaan dibatalkan",
+	"request_timed_out":      "Waktu permintaan habis",
+	"invalid_param":          "parameter tidak valid",
+	"invalid_body":           "isi permintaan tidak valid",
+	"request_body_too_large": "isi permintaan terlalu besar",
+	"unsupported_media_type": "tipe media tidak didukung",
+	"body_expected_json":     "harus application/json",
+	"body_single_value":      "isi harus berupa satu nilai JSON",
+	"body_limit":             "batasnya {0} byte",
+	"body_empty":             "isi kosong",
+	"body_incomplete":        "JSON pada isi tidak lengkap",
+	"body_malformed":         "JSON rusak pada offset {0}",
+	"body_not_json":          "isi bukan JSON yang valid",
+	"body_field_type":        "field {0} harus bertipe {1}",
+	"body_unknown_field":     "field {0} tidak dikenal",
+
+	"user_not_found":        "pengguna tidak ditemukan",
+	"email_taken":           "Email sudah digunakan",
+	"token_missing":         "autentikasi diperlukan",
+	"token_invalid":         "token tidak valid",
+	"refresh_token_invalid": "refresh token tidak valid atau kedaluwarsa",
+	"forbidden":             "anda tidak memiliki izin untuk melakukan ini",
+	"account_disabled":      "akun dinonaktifkan",
+	"admin_self_action":     "tidak dapat mengubah akun anda sendiri",
+	"password_wrong":        "Kata sandi salah",
+	"cat_not_found":         "kucing tidak ditemukan",
+	"cat_id_not_found":      "id tidak ditemukan",
+	"cat_sex_locked":        "Jenis kelamin tidak dapat diubah saat kucing sedang diajukan untuk dijodohkan",
+
+	"match_not_found":         "perjodohan tidak ditemukan",
+	"match_not_pending":       "perjodohan sudah tidak menunggu persetujuan",
+	"match_id_not_found":      "id perjodohan tidak ditemukan",
+	"match_id_not_valid":      "id perjodohan sudah tidak berlaku",
+	"match_already_processed": "perjodohan sudah disetujui / ditolak",
+	"match_not_issuer":        "Anda bukan pengaju perjodohan",
+	"user_cat_id_not_found":   "id kucing pengguna tidak ditemukan",
+	"match_cat_id_not_found":  "id kucing yang dijodohkan tidak ditemukan",
+	"user_cat_not_owned":      "userCatId bukan milik pengguna",
+	"match_same_sex":          "jenis kelamin tidak boleh sama",
+	"match_same_owner":        "tidak dapat menjodohkan kucing dengan pemilik yang sama",
+	"match_already_submitted": "Id kucing sudah diajukan untuk dijodohkan",
+
+	"password_too_short": "{0} panjang minimal {1} karakter",
+	"password_too_long":  "{0} panjang maksimal {1} karakter",
+	"password_weak":      "{0} terlalu mudah ditebak, buat lebih panjang atau campurkan huruf, angka dan simbol",
+	"password_common":    "{0} termasuk kata sandi yang paling umum",
+	"password_personal":  "{0} tidak boleh mengandung email atau nama anda",
+
+	"credentials_invalid": "email atau kata sandi salah",
+	"login_throttled":     "terlalu banyak login gagal, coba lagi nanti",
+	"login_locked":        "login dikunci karena terlalu banyak percobaan gagal, coba lagi nanti",
+
+	"mfa_already_enabled": "autentikasi dua faktor sudah aktif",
+	"mfa_not_enrolled":    "mulai pendaftaran autentikasi dua faktor terlebih dahulu",
+	"mfa_not_enabled":     "autentikasi dua faktor tidak aktif",
+	"mfa_code_invalid":    "kode tidak valid",
+	"mfa_token_invalid":   "token MFA tidak valid atau kedaluwarsa, silakan login kembali",
+
+	"session_not_found": "sesi tidak ditemukan",
+
+	"reset_token_invalid": "token reset tidak valid atau kedaluwarsa",
+
+	"mail_password_reset_subject": "Atur ulang kata sandi Cats Social anda",
+	"mail_password_reset_body":    "Seseorang meminta pengaturan ulang kata sandi akun Cats Social anda.\n\nBuka tautan ini untuk memilih kata sandi baru, tautan berlaku {0} menit:\n{1}\n\nJika itu bukan anda, abaikan email ini, kata sandi anda tidak berubah.",
+
+	"verification_token_invalid":    "token verifikasi tidak valid atau kedaluwarsa",
+	"email_already_verified":        "email sudah terverifikasi",
+	"verification_resend_throttled": "email verifikasi baru saja dikirim, coba lagi nanti",
+	"email_not_verified":            "verifikasi alamat email anda terlebih dahulu",
+
+	"email_unchanged":            "email baru sama dengan email saat ini",
+	"email_change_token_invalid": "token perubahan email tidak valid atau kedaluwarsa",
+
+	"mail_email_change_subject":  "Konfirmasi alamat email Cats Social baru anda",
+	"mail_email_change_body":     "Seseorang meminta perubahan email akun Cats Social ke alamat ini.\n\nBuka tautan ini untuk mengonfirmasi perubahan, tautan berlaku {0} jam:\n{1}\n\nJika itu bukan anda, abaikan email ini.",
+	"mail_email_changed_subject": "Alamat email Cats Social anda telah diubah",
+	"mail_email_changed_body":    "Email akun Cats Social anda telah diubah menjadi {0}.\n\nJika itu bukan anda, segera hubungi kami.",
+
+	"mail_verify_email_subject": "Verifikasi alamat email Cats Social anda",
+	"mail_verify_email_body":    "Selamat datang di Cats Social!\n\nBuka tautan ini untuk memverifikasi alamat email anda, tautan berlaku {0} jam:\n{1}\n\nJika anda tidak membuat akun, abaikan email ini.",
+}
@@ -0,0 +1,209 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SecretKeyId is the kid of the HMAC key made from JWT_SECRET.
+const SecretKeyId = "secret"
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is one key of the ring. A key loaded from a public key can only verify.
+type Key struct {
+	Id        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Keyring signs tokens with one key and verifies them with every key it
+// holds, so a new signing key can be rolled out while tokens signed by the
+// previous one stay valid until that key is removed.
+type Keyring struct {
+	keys    map[string]*Key
+	order   []string
+	signing *Key
+}
+
+// Load reads a comma separated list of kid=path pairs of PEM encoded RSA or
+// Ed25519 keys, e.g. "2026-10=/etc/cats/jwt-2026-10.pem". Tokens are signed
+// with signingKid, or the first private key of the list. A non-empty secret
+// is added as an HS512 key that signs only when there is no private key.
+func Load(keys string, signingKid string, secret string) (*Keyring, error) {
+	ring := &Keyring{keys: map[string]*Key{}}
+
+	for _, pair := range strings.Split(keys, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("keyring: %q is not a kid=path pair", pair)
+		}
+
+		key, err := loadKey(strings.TrimSpace(kid), strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+
+		err = ring.add(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if secret != "" {
+		err := ring.add(&Key{Id: SecretKeyId, Method: jwt.SigningMethodHS512, signKey: []byte(secret), verifyKey: []byte(secret)})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if signingKid != "" {
+		key, ok := ring.keys[signingKid]
+		if !ok || !key.CanSign() {
+			return nil, fmt.Errorf("keyring: signing key %q is not a private key of the ring", signingKid)
+		}
+		ring.signing = key
+
+		return ring, nil
+	}
+
+	for _, kid := range ring.order {
+		if key := ring.keys[kid]; key.CanSign() {
+			ring.signing = key
+			break
+		}
+	}
+
+	if ring.signing == nil {
+		return nil, errors.New("keyring: no private key or secret to sign tokens with")
+	}
+
+	return ring, nil
+}
+
+// Ephemeral returns a keyring holding a new Ed25519 key. Its tokens stop
+// verifying when the process exits, it is meant for local development.
+func Ephemeral() (*Keyring, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &Keyring{keys: map[string]*Key{}}
+	ring.signing = &Key{Id: "ephemeral", Method: jwt.SigningMethodEdDSA, signKey: private, verifyKey: public}
+
+	return ring, ring.add(ring.signing)
+}
+
+func (r *Keyring) add(key *Key) error {
+	if _, ok := r.keys[key.Id]; ok {
+		return fmt.Errorf("keyring: duplicate kid %q", key.Id)
+	}
+
+	r.keys[key.Id] = key
+	r.order = append(r.order, key.Id)
+
+	return nil
+}
+
+// Sign returns claims signed with the signing key, its kid is set in the header.
+func (r *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(r.signing.Method, claims)
+	token.Header["kid"] = r.signing.Id
+
+	return token.SignedString(r.signing.signKey)
+}
+
+// Keyfunc is a jwt.Keyfunc resolving the verification key from the kid
+// header. The algorithm of the token must be the one of the key, so a
+// public key can never be used as an HMAC secret.
+func (r *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("%w: kid %q does not sign %s", ErrUnknownKey, kid, token.Method.Alg())
+	}
+
+	return key.verifyKey, nil
+}
+
+// Methods returns the algorithms of the ring, for jwt.WithValidMethods.
+func (r *Keyring) Methods() []string {
+	methods := []string{}
+	seen := map[string]bool{}
+
+	for _, kid := range r.order {
+		alg := r.keys[kid].Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+
+	return methods
+}
+
+func loadKey(kid string, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: key %q: %w", kid, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("keyring: key %q: %s is not PEM encoded", kid, path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keyring: key %q: %w", kid, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{Id: kid, Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{Id: kid, Method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{Id: kid, Method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{Id: kid, Method: jwt.SigningMethodEdDSA, verifyKey: k}, nil
+	}
+
+	return nil, fmt.Errorf("keyring: key %q: only RSA and Ed25519 keys are supported", kid)
+}
@@ -0,0 +1,263 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey writes key PEM encoded to a file of dir and returns its path.
+func writeKey(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name+".pem")
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// testKeys writes an Ed25519 and an RSA key pair and returns the paths of
+// the private and public halves.
+func testKeys(t *testing.T) (map[string]string, *rsa.PrivateKey, ed25519.PublicKey) {
+	t.Helper()
+	dir := t.TempDir()
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPrivateDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	edPublicDER, _ := x509.MarshalPKIXPublicKey(edPublic)
+
+	paths := map[string]string{
+		"ed":          writeKey(t, dir, "ed", "PRIVATE KEY", edPrivateDER),
+		"ed.pub":      writeKey(t, dir, "ed.pub", "PUBLIC KEY", edPublicDER),
+		"rsa":         writeKey(t, dir, "rsa", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaPrivate)),
+		"rsa.pub":     writeKey(t, dir, "rsa.pub", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaPrivate.PublicKey)),
+		"not-pem":     filepath.Join(dir, "not-pem"),
+		"unsupported": writeKey(t, dir, "unsupported", "CERTIFICATE", []byte("cert")),
+	}
+	os.WriteFile(paths["not-pem"], []byte("not a key"), 0o600)
+
+	return paths, rsaPrivate, edPublic
+}
+
+// verify parses token with the keys of ring alone, without limiting the
+// methods, so the algorithm checks of Keyfunc are the ones tested.
+func verify(ring *Keyring, token string) error {
+	_, err := jwt.Parse(token, ring.Keyfunc)
+	return err
+}
+
+func TestLoad(t *testing.T) {
+	paths, _, _ := testKeys(t)
+
+	tests := []struct {
+		name       string
+		keys       string
+		signingKid string
+		secret     string
+		signer     string
+		err        bool
+	}{
+		{name: "first private key", keys: "a=" + paths["ed.pub"] + ",b=" + paths["rsa"] + ",c=" + paths["ed"], signer: "b"},
+		{name: "chosen kid", keys: "a=" + paths["rsa"] + ", b = " + paths["ed"], signingKid: "b", signer: "b"},
+		{name: "private key before the secret", keys: "a=" + paths["ed"], secret: "s3cret", signer: "a"},
+		{name: "secret only", secret: "s3cret", signer: SecretKeyId},
+		{name: "public keys and a secret", keys: "a=" + paths["ed.pub"] + ",b=" + paths["rsa.pub"], secret: "s3cret", signer: SecretKeyId},
+		{name: "public keys only", keys: "a=" + paths["ed.pub"], err: true},
+		{name: "nothing", err: true},
+		{name: "chosen public key", keys: "a=" + paths["ed.pub"] + ",b=" + paths["ed"], signingKid: "a", err: true},
+		{name: "chosen unknown kid", keys: "a=" + paths["ed"], signingKid: "z", err: true},
+		{name: "duplicate kid", keys: "a=" + paths["ed"] + ",a=" + paths["rsa"], err: true},
+		{name: "kid of the secret", keys: SecretKeyId + "=" + paths["ed"], secret: "s3cret", err: true},
+		{name: "no kid", keys: paths["ed"], err: true},
+		{name: "missing file", keys: "a=" + paths["ed"] + ".missing", err: true},
+		{name: "not PEM", keys: "a=" + paths["not-pem"], err: true},
+		{name: "unsupported PEM block", keys: "a=" + paths["unsupported"], err: true},
+	}
+
+	for _, tt := range tests {
+		ring, err := Load(tt.keys, tt.signingKid, tt.secret)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: got no error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if ring.signing.Id != tt.signer {
+			t.Errorf("%s: signs with %q, want %q", tt.name, ring.signing.Id, tt.signer)
+		}
+
+		token, err := ring.Sign(jwt.MapClaims{"sub": "u1"})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		parsed, err := jwt.Parse(token, ring.Keyfunc, jwt.WithValidMethods(ring.Methods()))
+		if err != nil {
+			t.Errorf("%s: own token: %v", tt.name, err)
+		} else if parsed.Header["kid"] != tt.signer {
+			t.Errorf("%s: got kid %v, want %q", tt.name, parsed.Header["kid"], tt.signer)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	paths, _, _ := testKeys(t)
+
+	old, err := Load("2026-09="+paths["ed"], "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := old.Sign(jwt.MapClaims{"sub": "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key signs, the public half of the old one still verifies
+	rotated, err := Load("2026-10="+paths["rsa"]+",2026-09="+paths["ed.pub"], "2026-10", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(rotated, oldToken); err != nil {
+		t.Errorf("token of the old key: %v", err)
+	}
+	if got := rotated.Methods(); !slices.Equal(got, []string{"RS256", "EdDSA"}) {
+		t.Errorf("got methods %v, want [RS256 EdDSA]", got)
+	}
+
+	newToken, err := rotated.Sign(jwt.MapClaims{"sub": "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(rotated, newToken); err != nil {
+		t.Errorf("token of the new key: %v", err)
+	}
+	if err := verify(old, newToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token of the new key on the old ring: got %v, want ErrUnknownKey", err)
+	}
+
+	// once the old key is removed its tokens stop verifying
+	removed, err := Load("2026-10="+paths["rsa"], "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(removed, oldToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token of the removed key: got %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestKeyfuncRejects(t *testing.T) {
+	paths, _, edPublic := testKeys(t)
+
+	ring, err := Load("ed="+paths["ed"]+",rsa="+paths["rsa.pub"], "", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid any, key any) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "u1"})
+		if kid != nil {
+			token.Header["kid"] = kid
+		}
+
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	_, stranger, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"unknown kid", sign(jwt.SigningMethodEdDSA, "other", stranger)},
+		{"no kid", sign(jwt.SigningMethodEdDSA, nil, stranger)},
+		{"kid not a string", sign(jwt.SigningMethodEdDSA, 7, stranger)},
+		{"public key as HMAC secret", sign(jwt.SigningMethodHS256, "ed", []byte(edPublic))},
+		{"secret under another alg", sign(jwt.SigningMethodHS256, SecretKeyId, []byte("s3cret"))},
+		{"RSA kid with an EdDSA token", sign(jwt.SigningMethodEdDSA, "rsa", stranger)},
+	}
+
+	for _, tt := range tests {
+		if err := verify(ring, tt.token); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("%s: got %v, want ErrUnknownKey", tt.name, err)
+		}
+	}
+
+	// the right kid and alg with the wrong key fail on the signature
+	err = verify(ring, sign(jwt.SigningMethodEdDSA, "ed", stranger))
+	if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Errorf("forged signature: got %v, want ErrTokenSignatureInvalid", err)
+	}
+
+	if err := verify(ring, sign(jwt.SigningMethodHS512, SecretKeyId, []byte("s3cret"))); err != nil {
+		t.Errorf("token of the secret: %v", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	paths, rsaPrivate, edPublic := testKeys(t)
+
+	ring, err := Load("rsa="+paths["rsa"]+",ed="+paths["ed.pub"], "", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := ring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("got %d keys, want the RSA and Ed25519 ones: %+v", len(set.Keys), set.Keys)
+	}
+
+	rsaKey, edKey := set.Keys[0], set.Keys[1]
+	if rsaKey.Kid != "rsa" || rsaKey.Kty != "RSA" || rsaKey.Alg != "RS256" || rsaKey.Use != "sig" || rsaKey.Crv != "" || rsaKey.X != "" {
+		t.Errorf("RSA key: got %+v", rsaKey)
+	}
+	n, _ := base64.RawURLEncoding.DecodeString(rsaKey.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rsaKey.E)
+	if new(big.Int).SetBytes(n).Cmp(rsaPrivate.N) != 0 || new(big.Int).SetBytes(e).Int64() != int64(rsaPrivate.E) {
+		t.Errorf("RSA key: n and e are not those of the key")
+	}
+
+	if edKey.Kid != "ed" || edKey.Kty != "OKP" || edKey.Crv != "Ed25519" || edKey.Alg != "EdDSA" || edKey.Use != "sig" || edKey.N != "" || edKey.E != "" {
+		t.Errorf("Ed25519 key: got %+v", edKey)
+	}
+	if x, _ := base64.RawURLEncoding.DecodeString(edKey.X); !slices.Equal(x, edPublic) {
+		t.Errorf("Ed25519 key: x is not the public key")
+	}
+
+	secretOnly, err := Load("", "", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set := secretOnly.JWKS(); set.Keys == nil || len(set.Keys) != 0 {
+		t.Errorf("secret only: got %+v, want an empty list", set)
+	}
+}
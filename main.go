@@ -1,29 +1,127 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/malikfajr/cats-social/auth"
 	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/db/migrations"
 	"github.com/malikfajr/cats-social/exception"
 	"github.com/malikfajr/cats-social/helper"
 	"github.com/malikfajr/cats-social/httpmux"
+	"github.com/malikfajr/cats-social/keyring"
+	"github.com/malikfajr/cats-social/mailer"
+	"github.com/malikfajr/cats-social/migrate"
 	"github.com/malikfajr/cats-social/models"
+	"github.com/malikfajr/cats-social/password"
 )
 
 func main() {
+	migrateOnStart := flag.Bool("migrate-on-start", false, "apply pending database migrations before serving")
+	flag.Parse()
+
 	config.InitEnv()
 	httpmux.InitValidator()
 
-	db, err := models.InitDb(config.GetDbAddress())
-	helper.PanicIfError(err)
-	defer db.Close()
-	log.Println("Database connected")
+	if flag.Arg(0) == "migrate" {
+		db, err := models.InitDb(config.GetDbAddress())
+		helper.PanicIfError(err)
+		defer db.Close()
+
+		err = migrate.Run(context.Background(), db, migrations.FS, flag.Args()[1:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "role" {
+		db, err := models.InitDb(config.GetDbAddress())
+		helper.PanicIfError(err)
+		defer db.Close()
+
+		err = setRole(context.Background(), models.NewPostgresStore(db, config.Env.DB_STATEMENT_TIMEOUT), flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var store models.Store
+	if config.Env.STORAGE == "memory" {
+		store = models.NewMemoryStore()
+		log.Println("Using in-memory storage")
+	} else {
+		db, err := models.InitDb(config.GetDbAddress())
+		helper.PanicIfError(err)
+		defer db.Close()
+		log.Println("Database connected")
+
+		if *migrateOnStart {
+			err = migrate.Run(context.Background(), db, migrations.FS, []string{"up"}, os.Stdout)
+			helper.PanicIfError(err)
+			log.Println("Database migrated")
+		}
 
-	db.SetMaxIdleConns(80)
+		db.SetMaxIdleConns(80)
+
+		db.SetMaxOpenConns(100)
+		store = models.NewPostgresStore(db, config.Env.DB_STATEMENT_TIMEOUT)
+	}
+	httpmux.InitStore(store)
+
+	switch config.Env.MAILER {
+	case "smtp":
+		httpmux.InitMailer(&mailer.SMTPMailer{
+			Host:     config.Env.SMTP_HOST,
+			Port:     config.Env.SMTP_PORT,
+			Username: config.Env.SMTP_USERNAME,
+			Password: config.Env.SMTP_PASSWORD,
+			From:     config.Env.MAIL_FROM,
+		})
+	case "file":
+		httpmux.InitMailer(&mailer.FileMailer{Dir: config.Env.MAIL_DIR, From: config.Env.MAIL_FROM})
+	default:
+		httpmux.InitMailer(&mailer.WriterMailer{W: os.Stdout, From: config.Env.MAIL_FROM})
+	}
+
+	passwordPolicy := password.Policy{
+		MinLength: config.Env.PASSWORD_MIN_LENGTH,
+		MaxLength: config.Env.PASSWORD_MAX_LENGTH,
+		MinScore:  config.Env.PASSWORD_MIN_SCORE,
+	}
+
+	switch config.Env.PASSWORD_HASHER {
+	case "bcrypt":
+		password.Init(password.Bcrypt{Cost: config.Env.BCRYPT_COST})
+		// bcrypt ignores everything after 72 bytes
+		passwordPolicy.MaxBytes = 72
+	default:
+		password.Init(password.Argon2id{
+			Memory:      uint32(config.Env.ARGON2_MEMORY),
+			Iterations:  uint32(config.Env.ARGON2_ITERATIONS),
+			Parallelism: uint8(config.Env.ARGON2_PARALLELISM),
+		})
+	}
+	password.InitPolicy(passwordPolicy)
+
+	var keys *keyring.Keyring
+	var err error
+	if config.Env.JWT_KEYS == "" && config.Env.JWT_SECRET == "" {
+		keys, err = keyring.Ephemeral()
+		log.Println("JWT_KEYS is not set, signing tokens with an ephemeral key")
+	} else {
+		keys, err = keyring.Load(config.Env.JWT_KEYS, config.Env.JWT_SIGNING_KEY, config.Env.JWT_SECRET)
+	}
+	helper.PanicIfError(err)
+	auth.Init(keys, store)
 
-	db.SetMaxOpenConns(100)
 	router := initializeRoutes()
 	wrapper := use(router, loggingMiddleware, exception.RecoverWrap)
 
@@ -38,6 +136,41 @@ func main() {
 	}
 }
 
+// setRole grants role to the user email from the command line, which is how
+// the first admin is created.
+func setRole(ctx context.Context, store models.Store, email string, role string) error {
+	switch role {
+	case models.RoleUser, models.RoleModerator, models.RoleAdmin:
+	default:
+		return fmt.Errorf("usage: role <email> <%s|%s|%s>", models.RoleUser, models.RoleModerator, models.RoleAdmin)
+	}
+
+	return models.WithTx(ctx, store, func(tx models.Tx) error {
+		user, err := tx.Users().GetUserByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Users().SetUserRole(ctx, user.Id, role)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Sessions().RevokeUserSessions(ctx, user.Id, "")
+		if err != nil {
+			return err
+		}
+
+		return tx.Audit().Record(ctx, models.AuditEntry{
+			ActorEmail: "cli",
+			Action:     "user.role",
+			TargetType: "user",
+			TargetId:   user.Id,
+			Detail:     "email " + email + ", " + user.Role + " -> " + role,
+		})
+	})
+}
+
 // wrapper global middleware
 func use(r *http.ServeMux, middlewares ...func(next http.Handler) http.Handler) http.Handler {
 	var s http.Handler
@@ -57,36 +190,27 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func authMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authorization := r.Header.Get("Authorization")
-
-		if authorization == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("invalid token"))
-			return
-		}
+// deadlineMiddleware cancels the request context once timeout has passed,
+// which also cancels the request's database transaction.
+func deadlineMiddleware(timeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-		tokenString := authorization[7:]
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
 
-		token, err := jwt.ParseWithClaims(tokenString, &config.CustomJWTClaim{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.Env.JWT_SECRET), nil
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
-		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("invalid token"))
-			return
-		}
-		if claims, ok := token.Claims.(*config.CustomJWTClaim); ok {
-			r.Header.Set("email", claims.Email)
-			r.Header.Set("name", claims.Name)
-			next.ServeHTTP(w, r)
-		} else {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("invalid token"))
-			return
-		}
-	})
+	}
+}
+
+// handle registers handler with the deadline configured for its pattern.
+func handle(mux *http.ServeMux, pattern string, handler http.Handler) {
+	mux.Handle(pattern, deadlineMiddleware(config.RouteTimeout(pattern))(handler))
 }
 
 func initializeRoutes() *http.ServeMux {
@@ -102,35 +226,100 @@ func initializeRoutes() *http.ServeMux {
 		helper.WriteToResponseBody(w, "hello world!", http.StatusOK)
 	})
 
-	mux.HandleFunc("POST /v1/user/register", httpmux.RegisterHandler)
-	mux.HandleFunc("POST /v1/user/login", httpmux.LoginHandler)
+	mux.HandleFunc("GET /.well-known/jwks.json", auth.JWKSHandler)
+
+	handle(mux, "POST /v1/user/register", httpmux.Handler(httpmux.RegisterHandler))
+	handle(mux, "POST /v1/user/login", httpmux.Handler(httpmux.LoginHandler))
+	handle(mux, "POST /v1/user/login/mfa", httpmux.Handler(httpmux.LoginMFA))
+	handle(mux, "POST /v1/user/token/refresh", httpmux.Handler(httpmux.RefreshTokenHandler))
+
+	Logout := httpmux.Handler(httpmux.LogoutHandler)
+	handle(mux, "POST /v1/user/logout", auth.Required(Logout))
+
+	GetMe := httpmux.Handler(httpmux.GetMe)
+	handle(mux, "GET /v1/user/me", auth.Required(GetMe))
+	UpdateMe := httpmux.Handler(httpmux.UpdateMe)
+	handle(mux, "PATCH /v1/user/me", auth.Required(UpdateMe))
+	DeleteMe := httpmux.Handler(httpmux.DeleteMe)
+	handle(mux, "DELETE /v1/user/me", auth.Required(DeleteMe))
+	handle(mux, "GET /v1/user/{id}", httpmux.Handler(httpmux.GetUser))
+
+	GetSessions := httpmux.Handler(httpmux.GetSessions)
+	handle(mux, "GET /v1/user/sessions", auth.Required(GetSessions))
+	RevokeAllSessions := httpmux.Handler(httpmux.RevokeAllSessions)
+	handle(mux, "DELETE /v1/user/sessions", auth.Required(RevokeAllSessions))
+	RevokeSession := httpmux.Handler(httpmux.RevokeSession)
+	handle(mux, "DELETE /v1/user/sessions/{id}", auth.Required(RevokeSession))
+
+	ChangePassword := httpmux.Handler(httpmux.ChangePassword)
+	handle(mux, "PUT /v1/user/password", auth.Required(ChangePassword))
+	handle(mux, "POST /v1/user/password/forgot", httpmux.Handler(httpmux.ForgotPassword))
+	handle(mux, "POST /v1/user/password/reset", httpmux.Handler(httpmux.ResetPassword))
+
+	EnrollTOTP := httpmux.Handler(httpmux.EnrollTOTP)
+	handle(mux, "POST /v1/user/mfa/totp", auth.Required(EnrollTOTP))
+	ConfirmTOTP := httpmux.Handler(httpmux.ConfirmTOTP)
+	handle(mux, "POST /v1/user/mfa/totp/confirm", auth.Required(ConfirmTOTP))
+	DisableTOTP := httpmux.Handler(httpmux.DisableTOTP)
+	handle(mux, "DELETE /v1/user/mfa/totp", auth.Required(DisableTOTP))
+	RegenerateRecoveryCodes := httpmux.Handler(httpmux.RegenerateRecoveryCodes)
+	handle(mux, "POST /v1/user/mfa/recovery-codes", auth.Required(RegenerateRecoveryCodes))
+
+	ChangeEmail := httpmux.Handler(httpmux.ChangeEmail)
+	handle(mux, "PUT /v1/user/email", auth.Required(ChangeEmail))
+	handle(mux, "GET /v1/user/email/confirm", httpmux.Handler(httpmux.ConfirmEmailChange))
+
+	handle(mux, "GET /v1/user/verify", httpmux.Handler(httpmux.VerifyEmail))
+	ResendVerification := httpmux.Handler(httpmux.ResendVerification)
+	handle(mux, "POST /v1/user/verify/resend", auth.Required(ResendVerification))
+
+	SaveCat := httpmux.Handler(httpmux.SaveCat)
+	handle(mux, "POST /v1/cat", auth.Required(SaveCat))
+
+	GetCat := httpmux.Handler(httpmux.GetCat)
+	handle(mux, "GET /v1/cat", auth.Required(GetCat))
+
+	NewMatch := httpmux.Handler(httpmux.CreateMatch)
+	handle(mux, "POST /v1/cat/match", auth.Required(NewMatch))
+
+	GetMatch := httpmux.Handler(httpmux.GetMyMatch)
+	handle(mux, "GET /v1/cat/match", auth.Required(GetMatch))
+
+	ApproveMatch := httpmux.Handler(httpmux.ApproveMatch)
+	handle(mux, "POST /v1/cat/match/approve", auth.Required(ApproveMatch))
+
+	RejectMatch := httpmux.Handler(httpmux.RejectMatch)
+	handle(mux, "POST /v1/cat/match/reject", auth.Required(RejectMatch))
+
+	DeleteMatch := httpmux.Handler(httpmux.DeleteMatch)
+	handle(mux, "DELETE /v1/cat/match/{id}", auth.Required(DeleteMatch))
 
-	SaveCat := http.HandlerFunc(httpmux.SaveCat)
-	mux.Handle("POST /v1/cat", authMiddleware(SaveCat))
+	UpdateCat := httpmux.Handler(httpmux.UpdateCat)
+	handle(mux, "PUT /v1/cat/{id}", auth.Required(UpdateCat))
 
-	GetCat := http.HandlerFunc(httpmux.GetCat)
-	mux.Handle("GET /v1/cat", authMiddleware(GetCat))
+	DeleteCat := httpmux.Handler(httpmux.DestroyCat)
+	handle(mux, "DELETE /v1/cat/{id}", auth.Required(DeleteCat))
 
-	NewMatch := http.HandlerFunc(httpmux.CreateMatch)
-	mux.Handle("POST /v1/cat/match", authMiddleware(NewMatch))
+	ListUsers := httpmux.Handler(httpmux.AdminListUsers)
+	handle(mux, "GET /v1/admin/users", auth.Required(auth.Permit(auth.ReadUsers)(ListUsers)))
 
-	GetMatch := http.HandlerFunc(httpmux.GetMyMatch)
-	mux.Handle("GET /v1/cat/match", authMiddleware(GetMatch))
+	SetRole := httpmux.Handler(httpmux.AdminSetRole)
+	handle(mux, "PUT /v1/admin/users/{id}/role", auth.Required(auth.Permit(auth.ManageUsers)(SetRole)))
 
-	ApproveMatch := http.HandlerFunc(httpmux.ApproveMatch)
-	mux.Handle("POST /v1/cat/match/approve", authMiddleware(ApproveMatch))
+	DisableUser := httpmux.Handler(httpmux.AdminDisableUser)
+	handle(mux, "POST /v1/admin/users/{id}/disable", auth.Required(auth.Permit(auth.ManageUsers)(DisableUser)))
 
-	RejectMatch := http.HandlerFunc(httpmux.RejectMatch)
-	mux.Handle("POST /v1/cat/match/reject", authMiddleware(RejectMatch))
+	EnableUser := httpmux.Handler(httpmux.AdminEnableUser)
+	handle(mux, "POST /v1/admin/users/{id}/enable", auth.Required(auth.Permit(auth.ManageUsers)(EnableUser)))
 
-	DeleteMatch := http.HandlerFunc(httpmux.DeleteMatch)
-	mux.Handle("DELETE /v1/cat/match/{id}", authMiddleware(DeleteMatch))
+	ForceDeleteCat := httpmux.Handler(httpmux.AdminDestroyCat)
+	handle(mux, "DELETE /v1/admin/cats/{id}", auth.Required(auth.Permit(auth.ManageCats)(ForceDeleteCat)))
 
-	UpdateCat := http.HandlerFunc(httpmux.UpdateCat)
-	mux.Handle("PUT /v1/cat/{id}", authMiddleware(UpdateCat))
+	CancelMatch := httpmux.Handler(httpmux.AdminCancelMatch)
+	handle(mux, "POST /v1/admin/matches/{id}/cancel", auth.Required(auth.Permit(auth.ManageMatches)(CancelMatch)))
 
-	DeleteCat := http.HandlerFunc(httpmux.DestroyCat)
-	mux.Handle("DELETE /v1/cat/{id}", authMiddleware(DeleteCat))
+	ListAudit := httpmux.Handler(httpmux.AdminListAudit)
+	handle(mux, "GET /v1/admin/audit", auth.Required(auth.Permit(auth.ReadAudit)(ListAudit)))
 
 	return mux
 }
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/malikfajr/cats-social/auth"
+	"github.com/malikfajr/cats-social/config"
+	"github.com/malikfajr/cats-social/httpmux"
+	"github.com/malikfajr/cats-social/keyring"
+	"github.com/malikfajr/cats-social/mailer"
+	"github.com/malikfajr/cats-social/models"
+	"github.com/malikfajr/cats-social/password"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const testPassword = "correct horse battery"
+
+var (
+	testStore   models.Store
+	testHandler http.Handler
+)
+
+// TestMain serves the routes over the memory store, the validator and the
+// keyring can only be set up once per process.
+func TestMain(m *testing.M) {
+	os.Setenv("EMAIL_VERIFICATION_REQUIRED", "none")
+	config.InitEnv()
+	httpmux.InitValidator()
+
+	testStore = models.NewMemoryStore()
+	httpmux.InitStore(testStore)
+
+	keys, err := keyring.Ephemeral()
+	if err != nil {
+		panic(err)
+	}
+	auth.Init(keys, testStore)
+
+	// cheap parameters, the hashes are not what is tested here
+	password.Init(password.Argon2id{Memory: 8 * 1024, Iterations: 1, Parallelism: 1})
+	password.InitPolicy(password.Policy{MinLength: 8, MaxLength: 128, MinScore: 2})
+	httpmux.InitMailer(&mailer.WriterMailer{W: io.Discard})
+
+	testHandler = initializeRoutes()
+
+	os.Exit(m.Run())
+}
+
+// request sends body as JSON to the routes and returns the status and the
+// "data" of the response.
+func request(t *testing.T, method string, path string, token string, body string) (int, any) {
+	t.Helper()
+
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, path, nil)
+	} else {
+		r = httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	testHandler.ServeHTTP(w, r)
+
+	response := struct {
+		Data any `json:"data"`
+	}{}
+	if w.Body.Len() > 0 {
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatalf("%s %s: response %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+
+	return w.Code, response.Data
+}
+
+// expect sends the request and fails the test unless it answers status.
+func expect(t *testing.T, status int, method string, path string, token string, body string) map[string]any {
+	t.Helper()
+
+	code, data := request(t, method, path, token, body)
+	if code != status {
+		t.Fatalf("%s %s: got status %d, want %d", method, path, code, status)
+	}
+
+	object, _ := data.(map[string]any)
+	return object
+}
+
+// register signs a new user up and returns its access token.
+func register(t *testing.T, name string, email string) string {
+	t.Helper()
+
+	data := expect(t, http.StatusCreated, "POST", "/v1/user/register", "",
+		`{"name":"`+name+`","email":"`+email+`","password":"`+testPassword+`"}`)
+
+	token, _ := data["accessToken"].(string)
+	if token == "" {
+		t.Fatalf("register %s: no access token in %v", email, data)
+	}
+
+	return token
+}
+
+// saveCat creates a cat and returns its id.
+func saveCat(t *testing.T, token string, name string, sex string) string {
+	t.Helper()
+
+	data := expect(t, http.StatusCreated, "POST", "/v1/cat", token,
+		`{"name":"`+name+`","race":"Persian","sex":"`+sex+`","ageInMonth":4,"description":"a cat","imageUrls":["https://example.com/cat.png"]}`)
+
+	return data["id"].(string)
+}
+
+// listCats returns the cats of GET /v1/cat with query.
+func listCats(t *testing.T, token string, query string) []any {
+	t.Helper()
+
+	code, data := request(t, "GET", "/v1/cat?"+query, token, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET /v1/cat?%s: got status %d", query, code)
+	}
+
+	cats, _ := data.([]any)
+	return cats
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	token := register(t, "Login User", "login@example.com")
+
+	me := expect(t, http.StatusOK, "GET", "/v1/user/me", token, "")
+	if me["email"] != "login@example.com" || me["name"] != "Login User" {
+		t.Errorf("GET /v1/user/me: got %v", me)
+	}
+
+	expect(t, http.StatusConflict, "POST", "/v1/user/register", "",
+		`{"name":"Login User","email":"login@example.com","password":"`+testPassword+`"}`)
+	expect(t, http.StatusBadRequest, "POST", "/v1/user/register", "",
+		`{"name":"Weak User","email":"weak@example.com","password":"password"}`)
+
+	expect(t, http.StatusBadRequest, "POST", "/v1/user/login", "",
+		`{"email":"login@example.com","password":"not the password"}`)
+	expect(t, http.StatusBadRequest, "POST", "/v1/user/login", "",
+		`{"email":"nobody@example.com","password":"`+testPassword+`"}`)
+
+	data := expect(t, http.StatusOK, "POST", "/v1/user/login", "",
+		`{"email":"login@example.com","password":"`+testPassword+`"}`)
+	if data["accessToken"] == "" || data["refreshToken"] == "" {
+		t.Errorf("login: got %v", data)
+	}
+
+	expect(t, http.StatusOK, "GET", "/v1/user/me", data["accessToken"].(string), "")
+	expect(t, http.StatusUnauthorized, "GET", "/v1/user/me", "", "")
+	expect(t, http.StatusUnauthorized, "GET", "/v1/user/me", "not-a-token", "")
+}
+
+// passwordHash returns the stored password hash of the user of email.
+func passwordHash(t *testing.T, email string) string {
+	t.Helper()
+
+	var user models.User
+	err := models.WithTx(context.Background(), testStore, func(tx models.Tx) (err error) {
+		user, err = tx.Users().GetUserByEmail(context.Background(), email)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user.Password
+}
+
+func TestLoginRehash(t *testing.T) {
+	hasher := password.Argon2id{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}
+	defer password.Init(hasher)
+
+	// signed up while bcrypt was configured
+	password.Init(password.Bcrypt{Cost: 4})
+	register(t, "Rehash User", "rehash@example.com")
+	old := passwordHash(t, "rehash@example.com")
+	if !strings.HasPrefix(old, "$2a$04$") {
+		t.Fatalf("hash before the change: got %q", old)
+	}
+
+	password.Init(hasher)
+	login := `{"email":"rehash@example.com","password":"` + testPassword + `"}`
+
+	// a failed login leaves the hash alone
+	expect(t, http.StatusBadRequest, "POST", "/v1/user/login", "", `{"email":"rehash@example.com","password":"not the password"}`)
+	if got := passwordHash(t, "rehash@example.com"); got != old {
+		t.Errorf("hash after a failed login: got %q, want %q", got, old)
+	}
+
+	expect(t, http.StatusOK, "POST", "/v1/user/login", "", login)
+	upgraded := passwordHash(t, "rehash@example.com")
+	if !hasher.Owns(upgraded) || !hasher.Current(upgraded) {
+		t.Fatalf("hash after login: got %q, want an argon2id hash", upgraded)
+	}
+
+	// a current hash is kept as it is
+	expect(t, http.StatusOK, "POST", "/v1/user/login", "", login)
+	if got := passwordHash(t, "rehash@example.com"); got != upgraded {
+		t.Errorf("hash after a second login: got %q, want %q", got, upgraded)
+	}
+}
+
+func TestCatCRUD(t *testing.T) {
+	owner := register(t, "Cat Owner", "owner@example.com")
+	other := register(t, "Other Owner", "other@example.com")
+
+	id := saveCat(t, owner, "Tom", "male")
+
+	cats := listCats(t, owner, "id="+id)
+	if len(cats) != 1 || cats[0].(map[string]any)["name"] != "Tom" {
+		t.Fatalf("GET /v1/cat?id=%s: got %v", id, cats)
+	}
+
+	expect(t, http.StatusBadRequest, "POST", "/v1/cat", owner, `{"name":"Tom"}`)
+
+	expect(t, http.StatusOK, "PUT", "/v1/cat/"+id, owner,
+		`{"name":"Thomas","race":"Bengal","sex":"male","ageInMonth":5,"description":"renamed","imageUrls":["https://example.com/cat.png"]}`)
+	cat := listCats(t, owner, "id="+id)[0].(map[string]any)
+	if cat["name"] != "Thomas" || cat["race"] != "Bengal" || cat["ageInMonth"] != 5.0 {
+		t.Errorf("after PUT /v1/cat/%s: got %v", id, cat)
+	}
+
+	// only the owner changes or deletes a cat
+	expect(t, http.StatusNotFound, "PUT", "/v1/cat/"+id, other,
+		`{"name":"Stolen","race":"Bengal","sex":"male","ageInMonth":5,"description":"mine","imageUrls":["https://example.com/cat.png"]}`)
+	expect(t, http.StatusNotFound, "DELETE", "/v1/cat/"+id, other, "")
+
+	if cats := listCats(t, other, "owned=true"); len(cats) != 0 {
+		t.Errorf("GET /v1/cat?owned=true of another user: got %v", cats)
+	}
+
+	expect(t, http.StatusOK, "DELETE", "/v1/cat/"+id, owner, "")
+	expect(t, http.StatusNotFound, "DELETE", "/v1/cat/"+id, owner, "")
+	if cats := listCats(t, owner, "id="+id); len(cats) != 0 {
+		t.Errorf("GET /v1/cat?id=%s after DELETE: got %v", id, cats)
+	}
+}
+
+// matchIds returns the ids of the matches listed to token.
+func matchIds(t *testing.T, token string) []string {
+	t.Helper()
+
+	code, data := request(t, "GET", "/v1/cat/match", token, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET /v1/cat/match: got status %d", code)
+	}
+
+	ids := []string{}
+	for _, match := range data.([]any) {
+		ids = append(ids, match.(map[string]any)["id"].(string))
+	}
+
+	return ids
+}
+
+func TestMatchFlow(t *testing.T) {
+	issuer := register(t, "Match Issuer", "issuer@example.com")
+	receiver := register(t, "Match Receiver", "receiver@example.com")
+
+	tom := saveCat(t, issuer, "Tom", "male")
+	kitty := saveCat(t, receiver, "Kitty", "female")
+	molly := saveCat(t, receiver, "Molly", "female")
+	luna := saveCat(t, receiver, "Luna", "female")
+	leo := saveCat(t, receiver, "Leo", "male")
+
+	newMatch := func(token string, userCat string, matchCat string) string {
+		t.Helper()
+
+		data := expect(t, http.StatusCreated, "POST", "/v1/cat/match", token,
+			`{"userCatId":"`+userCat+`","matchCatId":"`+matchCat+`","message":"shall we meet?"}`)
+
+		return data["matchId"].(string)
+	}
+
+	expect(t, http.StatusBadRequest, "POST", "/v1/cat/match", issuer,
+		`{"userCatId":"`+tom+`","matchCatId":"`+leo+`","message":"shall we meet?"}`)
+	expect(t, http.StatusNotFound, "POST", "/v1/cat/match", issuer,
+		`{"userCatId":"`+kitty+`","matchCatId":"`+tom+`","message":"shall we meet?"}`)
+
+	// delete: only the issuer withdraws a pending match
+	withdrawn := newMatch(issuer, tom, molly)
+	expect(t, http.StatusBadRequest, "DELETE", "/v1/cat/match/"+withdrawn, receiver, "")
+	expect(t, http.StatusOK, "DELETE", "/v1/cat/match/"+withdrawn, issuer, "")
+	expect(t, http.StatusNotFound, "DELETE", "/v1/cat/match/"+withdrawn, issuer, "")
+
+	// reject: the receiver turns the match down, it cannot be approved anymore
+	rejected := newMatch(issuer, tom, molly)
+	expect(t, http.StatusBadRequest, "POST", "/v1/cat/match", issuer,
+		`{"userCatId":"`+tom+`","matchCatId":"`+molly+`","message":"shall we meet?"}`)
+	if ids := matchIds(t, receiver); len(ids) != 1 || ids[0] != rejected {
+		t.Errorf("matches of the receiver: got %v, want [%s]", ids, rejected)
+	}
+	expect(t, http.StatusNotFound, "POST", "/v1/cat/match/reject", issuer, `{"matchId":"`+rejected+`"}`)
+	expect(t, http.StatusOK, "POST", "/v1/cat/match/reject", receiver, `{"matchId":"`+rejected+`"}`)
+	expect(t, http.StatusBadRequest, "POST", "/v1/cat/match/approve", receiver, `{"matchId":"`+rejected+`"}`)
+
+	// approve: both cats have matched and the other requests of the cats end
+	approved := newMatch(issuer, tom, kitty)
+	pending := newMatch(receiver, luna, tom)
+	expect(t, http.StatusOK, "POST", "/v1/cat/match/approve", receiver, `{"matchId":"`+approved+`"}`)
+	expect(t, http.StatusBadRequest, "POST", "/v1/cat/match/approve", receiver, `{"matchId":"`+approved+`"}`)
+	expect(t, http.StatusBadRequest, "DELETE", "/v1/cat/match/"+approved, issuer, "")
+	expect(t, http.StatusBadRequest, "POST", "/v1/cat/match/approve", issuer, `{"matchId":"`+pending+`"}`)
+
+	for _, id := range []string{tom, kitty} {
+		cat := listCats(t, receiver, "id="+id)[0].(map[string]any)
+		if cat["hasMatched"] != true {
+			t.Errorf("cat %s after approval: got %v", id, cat)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	token := register(t, "MFA User", "mfa@example.com")
+	login := `{"email":"mfa@example.com","password":"` + testPassword + `"}`
+
+	expect(t, http.StatusBadRequest, "POST", "/v1/user/mfa/totp/confirm", token, `{"code":"123456"}`)
+
+	secret, _ := expect(t, http.StatusCreated, "POST", "/v1/user/mfa/totp", token, "")["secret"].(string)
+	if secret == "" {
+		t.Fatal("enroll: no secret")
+	}
+
+	// the codes of the current and of the next time step
+	now := time.Now()
+	code := func(steps int) string {
+		t.Helper()
+
+		c, err := totp.GenerateCodeCustom(secret, now.Add(time.Duration(steps)*30*time.Second), totp.ValidateOpts{
+			Period:    30,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	expect(t, http.StatusBadRequest, "POST", "/v1/user/mfa/totp/confirm", token, `{"code":"abcdef"}`)
+	data := expect(t, http.StatusOK, "POST", "/v1/user/mfa/totp/confirm", token, `{"code":"`+code(0)+`"}`)
+	recoveryCodes, _ := data["recoveryCodes"].([]any)
+	if len(recoveryCodes) != 10 {
+		t.Fatalf("confirm: got recovery codes %v", data["recoveryCodes"])
+	}
+	expect(t, http.StatusConflict, "POST", "/v1/user/mfa/totp", token, "")
+
+	// the password alone only starts the login
+	data = expect(t, http.StatusOK, "POST", "/v1/user/login", "", login)
+	mfaToken, _ := data["mfaToken"].(string)
+	if data["mfaRequired"] != true || mfaToken == "" || data["accessToken"] != nil {
+		t.Fatalf("login with TOTP on: got %v", data)
+	}
+
+	// a code is used once, the one that confirmed the enrollment is spent
+	expect(t, http.StatusBadRequest, "POST", "/v1/user/login/mfa", "", `{"mfaToken":"`+mfaToken+`","code":"`+code(0)+`"}`)
+	data = expect(t, http.StatusOK, "POST", "/v1/user/login/mfa", "", `{"mfaToken":"`+mfaToken+`","code":"`+code(1)+`"}`)
+	if data["accessToken"] == nil {
+		t.Fatalf("login with a code: got %v", data)
+	}
+	expect(t, http.StatusBadRequest, "POST", "/v1/user/login/mfa", "", `{"mfaToken":"`+mfaToken+`","code":"`+code(1)+`"}`)
+
+	// disable: a spent code and a wrong one are refused, a recovery code is
+	// accepted however it is typed
+	expect(t, http.StatusBadRequest, "DELETE", "/v1/user/mfa/totp", token, `{"code":"`+code(1)+`"}`)
+	expect(t, http.StatusBadRequest, "DELETE", "/v1/user/mfa/totp", token, `{"code":"abcdef"}`)
+	recovery := strings.ToLower(strings.ReplaceAll(recoveryCodes[0].(string), "-", ""))
+	expect(t, http.StatusOK, "DELETE", "/v1/user/mfa/totp", token, `{"code":"`+recovery+`"}`)
+	expect(t, http.StatusBadRequest, "DELETE", "/v1/user/mfa/totp", token, `{"code":"`+recovery+`"}`)
+
+	data = expect(t, http.StatusOK, "POST", "/v1/user/login", "", login)
+	if data["mfaRequired"] == true || data["accessToken"] == nil {
+		t.Errorf("login with TOTP off: got %v", data)
+	}
+}
+
+func TestUpdateKeepsPrivate(t *testing.T) {
+	owner := register(t, "Private Owner", "private@example.com")
+	other := register(t, "Curious User", "curious@example.com")
+
+	data := expect(t, http.StatusCreated, "POST", "/v1/cat", owner,
+		`{"name":"Shy","race":"Birman","sex":"female","ageInMonth":4,"description":"a shy cat","imageUrls":["https://example.com/cat.png"],"private":true}`)
+	id := data["id"].(string)
+
+	update := func(private string) {
+		t.Helper()
+		expect(t, http.StatusOK, "PUT", "/v1/cat/"+id, owner,
+			`{"name":"Shy","race":"Birman","sex":"female","ageInMonth":5,"description":"older"`+private+`,"imageUrls":["https://example.com/cat.png"]}`)
+	}
+	private := func() bool {
+		t.Helper()
+		cats := listCats(t, owner, "id="+id)
+		if len(cats) != 1 {
+			t.Fatalf("GET /v1/cat?id=%s of the owner: got %v", id, cats)
+		}
+		return cats[0].(map[string]any)["private"] == true
+	}
+
+	// clients older than the field leave it alone
+	update("")
+	if !private() || len(listCats(t, other, "id="+id)) != 0 {
+		t.Errorf("update without private: the cat is not private anymore")
+	}
+
+	update(`,"private":false`)
+	if private() || len(listCats(t, other, "id="+id)) != 1 {
+		t.Errorf("update with private false: the cat is still private")
+	}
+
+	update("")
+	if private() {
+		t.Errorf("update without private: the cat turned private")
+	}
+}
+
+func TestAdminDeletesKeptCat(t *testing.T) {
+	register(t, "Cat Moderator", "moderator@example.com")
+	err := setRole(context.Background(), testStore, "moderator@example.com", models.RoleModerator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moderator, _ := expect(t, http.StatusOK, "POST", "/v1/user/login", "",
+		`{"email":"moderator@example.com","password":"`+testPassword+`"}`)["accessToken"].(string)
+
+	leaver := register(t, "Leaving User", "leaver@example.com")
+	stayer := register(t, "Staying User", "stayer@example.com")
+	kept := saveCat(t, leaver, "Oscar", "male")
+	partner := saveCat(t, stayer, "Nala", "female")
+
+	// issued by the staying user, who is the one listing approved matches
+	match := expect(t, http.StatusCreated, "POST", "/v1/cat/match", stayer,
+		`{"userCatId":"`+partner+`","matchCatId":"`+kept+`","message":"shall we meet?"}`)["matchId"].(string)
+	expect(t, http.StatusOK, "POST", "/v1/cat/match/approve", leaver, `{"matchId":"`+match+`"}`)
+
+	expect(t, http.StatusOK, "DELETE", "/v1/user/me", leaver, `{"password":"`+testPassword+`"}`)
+	if ids := matchIds(t, stayer); len(ids) != 1 || ids[0] != match {
+		t.Fatalf("matches after the account deletion: got %v, want [%s]", ids, match)
+	}
+
+	// the cat has no owner anymore, a moderator still removes it
+	expect(t, http.StatusOK, "DELETE", "/v1/admin/cats/"+kept, moderator, "")
+	expect(t, http.StatusNotFound, "DELETE", "/v1/admin/cats/"+kept, moderator, "")
+	if ids := matchIds(t, stayer); len(ids) != 0 {
+		t.Errorf("matches after the cat deletion: got %v", ids)
+	}
+}
+
+func TestPasswordConfirmationThrottled(t *testing.T) {
+	token := register(t, "Guessed User", "guessed@example.com")
+
+	// the failures of every confirmation add up on the account
+	expect(t, http.StatusBadRequest, "PUT", "/v1/user/password", token, `{"currentPassword":"guess 1","newPassword":"another horse battery"}`)
+	expect(t, http.StatusBadRequest, "PUT", "/v1/user/email", token, `{"email":"guessed2@example.com","password":"guess 2"}`)
+	expect(t, http.StatusBadRequest, "DELETE", "/v1/user/me", token, `{"password":"guess 3"}`)
+	expect(t, http.StatusBadRequest, "DELETE", "/v1/user/me", token, `{"password":"guess 4"}`)
+
+	// past the free attempts even the right password waits, as a login does
+	expect(t, http.StatusTooManyRequests, "DELETE", "/v1/user/me", token, `{"password":"`+testPassword+`"}`)
+	expect(t, http.StatusTooManyRequests, "POST", "/v1/user/login", "", `{"email":"guessed@example.com","password":"`+testPassword+`"}`)
+	expect(t, http.StatusOK, "GET", "/v1/user/me", token, "")
+}
@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up                apply all pending migrations
+  down <n>|-all     roll back the last n migrations, or all of them with -all
+  status            print the current version and every migration
+  force <version>   set the version without running migrations, -1 clears it`
+
+// Run executes the migrate subcommand described by args and writes its
+// output to out.
+func Run(ctx context.Context, db *sql.DB, fsys fs.FS, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	m, err := New(db, fsys)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+
+	case "down":
+		// rolling back everything drops every table, it is never the default
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		if args[1] == "-all" {
+			return m.DownAll(ctx)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of migrations %q", args[1])
+		}
+		return m.Down(ctx, n)
+
+	case "force":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return m.Force(ctx, version)
+
+	case "status":
+		version, dirty, statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		if version == NilVersion {
+			fmt.Fprintln(out, "version: none")
+		} else {
+			fmt.Fprintf(out, "version: %d (dirty: %t)\n", version, dirty)
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(out, "%d_%s\t%s\n", status.Migration.Version, status.Migration.Name, state)
+		}
+		return nil
+
+	default:
+		return errors.New(usage)
+	}
+}
@@ -0,0 +1,281 @@
+// Package migrate applies the SQL files in db/migrations. Applied versions
+// are tracked in the same schema_migrations table golang-migrate uses, so a
+// database can be moved between the two tools freely.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NilVersion is the version of a database without any migration applied.
+const NilVersion = -1
+
+const lockKey = 20240428
+
+var fileRegex = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+type ErrDirty struct {
+	Version int64
+}
+
+func (e ErrDirty) Error() string {
+	return fmt.Sprintf("dirty database version %d, fix and force version", e.Version)
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		m := fileRegex.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+
+		if m[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every migration newer than the current version.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return ErrDirty{Version: version}
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+
+			err := run(ctx, conn, migration.Version, migration.Up)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down rolls back the last n applied migrations, n must be at least 1.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n < 1 {
+		return fmt.Errorf("invalid number of migrations %d", n)
+	}
+
+	return m.down(ctx, n)
+}
+
+// DownAll rolls back every applied migration.
+func (m *Migrator) DownAll(ctx context.Context) error {
+	return m.down(ctx, 0)
+}
+
+// down rolls back the last n applied migrations, or all of them when n is 0.
+func (m *Migrator) down(ctx context.Context, n int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return ErrDirty{Version: version}
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+			if migration.Version < version {
+				return fmt.Errorf("no migration found for version %d", version)
+			}
+
+			target := int64(NilVersion)
+			if i > 0 {
+				target = m.migrations[i-1].Version
+			}
+
+			err := run(ctx, conn, target, migration.Down)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+
+			version = target
+			n--
+			if n == 0 {
+				break
+			}
+		}
+
+		return nil
+	})
+}
+
+// Force sets the version without running any migration and clears the dirty flag.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version < NilVersion {
+		return fmt.Errorf("invalid version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+type Status struct {
+	Migration Migration
+	Applied   bool
+}
+
+// Status returns the current version, its dirty flag and every known migration.
+func (m *Migrator) Status(ctx context.Context) (int64, bool, []Status, error) {
+	var version int64
+	var dirty bool
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn) (err error) {
+		version, dirty, err = currentVersion(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return version, dirty, nil, err
+	}
+
+	for _, migration := range m.migrations {
+		statuses = append(statuses, Status{
+			Migration: migration,
+			Applied:   migration.Version <= version,
+		})
+	}
+
+	return version, dirty, statuses, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey)
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func currentVersion(ctx context.Context, conn *sql.Conn) (int64, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := conn.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NilVersion, false, nil
+	}
+
+	return version, dirty, err
+}
+
+func setVersion(ctx context.Context, conn *sql.Conn, version int64, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "TRUNCATE schema_migrations")
+	if err == nil && version >= 0 {
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", version, dirty)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// run executes one migration file the way golang-migrate does: the target
+// version is recorded as dirty first and only marked clean once the body
+// succeeded, so a failed migration has to be fixed and forced by hand.
+func run(ctx context.Context, conn *sql.Conn, target int64, body string) error {
+	err := setVersion(ctx, conn, target, true)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(body) != "" {
+		_, err = conn.ExecContext(ctx, body)
+		if err != nil {
+			return err
+		}
+	}
+
+	return setVersion(ctx, conn, target, false)
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// AuditEntry records an action taken on behalf of someone else, e.g. by
+// support staff through the admin API. The actor and the users acted on are
+// kept by id, ActorEmail is the email of the actor when the entry was
+// recorded and the only actor of a login with an unknown email.
+type AuditEntry struct {
+	Id         int64     `json:"id"`
+	ActorId    string    `json:"actorId"`
+	ActorEmail string    `json:"actorEmail"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"targetType"`
+	TargetId   string    `json:"targetId"`
+	Detail     string    `json:"detail"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type AuditParam struct {
+	ActorId    string
+	ActorEmail string
+	Action     string
+	TargetId   string
+	Limit      string
+	Offset     string
+}
+
+// auditFilter is the parsed form of AuditParam shared by every AuditStore.
+type auditFilter struct {
+	actorId    string
+	actorEmail string
+	action     string
+	targetId   string
+	limit      int
+	offset     int
+}
+
+func (auditParam AuditParam) filter() (auditFilter, error) {
+	f := auditFilter{
+		actorId:    auditParam.ActorId,
+		actorEmail: auditParam.ActorEmail,
+		action:     auditParam.Action,
+		targetId:   auditParam.TargetId,
+		limit:      50,
+	}
+
+	if f.actorId != "" && !uuidPattern.MatchString(f.actorId) {
+		return f, fmt.Errorf("%w: actorId %q", ErrInvalidParam, f.actorId)
+	}
+
+	if auditParam.Limit != "" {
+		limit, err := strconv.Atoi(auditParam.Limit)
+		if err != nil || limit < 1 || limit > 200 {
+			return f, fmt.Errorf("%w: limit %q", ErrInvalidParam, auditParam.Limit)
+		}
+		f.limit = limit
+	}
+
+	if auditParam.Offset != "" {
+		offset, err := strconv.Atoi(auditParam.Offset)
+		if err != nil || offset < 0 {
+			return f, fmt.Errorf("%w: offset %q", ErrInvalidParam, auditParam.Offset)
+		}
+		f.offset = offset
+	}
+
+	return f, nil
+}
+
+type postgresAuditStore struct {
+	tx *sql.Tx
+}
+
+func (s *postgresAuditStore) Record(ctx context.Context, entry AuditEntry) error {
+	SQL := `INSERT INTO audit_logs (actor_id, actor_email, action, target_type, target_id, detail)
+			VALUES (NULLIF($1, '')::UUID, $2, $3, $4, $5, $6)`
+
+	_, err := s.tx.ExecContext(ctx, SQL, entry.ActorId, entry.ActorEmail, entry.Action, entry.TargetType, entry.TargetId, entry.Detail)
+
+	return err
+}
+
+func (s *postgresAuditStore) GetAllAudit(ctx context.Context, auditParam AuditParam) ([]AuditEntry, error) {
+	SQL := "SELECT id, COALESCE(actor_id::TEXT, ''), actor_email, action, target_type, target_id, detail, created_at FROM audit_logs WHERE TRUE"
+
+	params := make([]interface{}, 0)
+	f, err := auditParam.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.actorId != "" {
+		SQL += fmt.Sprintf(" AND actor_id = $%d", len(params)+1)
+		params = append(params, f.actorId)
+	}
+
+	if f.actorEmail != "" {
+		SQL += fmt.Sprintf(" AND actor_email = $%d", len(params)+1)
+		params = append(params, f.actorEmail)
+	}
+
+	if f.action != "" {
+		SQL += fmt.Sprintf(" AND action = $%d", len(params)+1)
+		params = append(params, f.action)
+	}
+
+	if f.targetId != "" {
+		SQL += fmt.Sprintf(" AND target_id = $%d", len(params)+1)
+		params = append(params, f.targetId)
+	}
+
+	SQL += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT %d OFFSET %d", f.limit, f.offset)
+
+	rows, err := s.tx.QueryContext(ctx, SQL, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		entry := AuditEntry{}
+		err := rows.Scan(&entry.Id, &entry.ActorId, &entry.ActorEmail, &entry.Action, &entry.TargetType, &entry.TargetId, &entry.Detail, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
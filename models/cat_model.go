@@ -2,229 +2,530 @@ package models
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
-	"errors"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/lib/pq"
-	"github.com/malikfajr/cats-social/helper"
 )
 
 type Cat struct {
-	Id          string    `json:"id"`
-	UserEmail   string    `json:"-"`
-	Name        string    `json:"name"`
-	Race        string    `json:"race"`
-	Sex         string    `json:"sex"`
-	AgeInMonth  int       `json:"ageInMonth"`
-	ImageUrls   []string  `json:"imageUrls"`
-	Description string    `json:"description"`
-	HasMatched  bool      `json:"hasMatched"`
-	CreatedAt   time.Time `json:"createdAt"`
+	// Id is the ULID of the cat, its sequential id never leaves the store.
+	Id          string   `json:"id"`
+	UserId      string   `json:"-"`
+	Name        string   `json:"name"`
+	Race        string   `json:"race"`
+	Sex         string   `json:"sex"`
+	AgeInMonth  int      `json:"ageInMonth"`
+	ImageUrls   []string `json:"imageUrls"`
+	Description string   `json:"description"`
+	HasMatched  bool     `json:"hasMatched"`
+	// Private cats are listed to their owner only.
+	Private   bool      `json:"private"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// relevance is the similarity of the name to the search, when sorted by it
+	relevance float64
 }
 
 type CatInsertRequest struct {
-	UserEmail   string   `json:"userEmail"`
+	UserId      string   `json:"-"`
 	Name        string   `json:"name" validate:"required,min=1,max=30"`
 	Race        string   `json:"race" validate:"required,oneof='Persian' 'Maine Coon' 'Siamese' 'Ragdoll' 'Bengal' 'Sphynx' 'British Shorthair' 'Abyssinian' 'Scottish Fold' 'Birman'"`
 	Sex         string   `json:"sex" validate:"required,oneof=male female"`
 	AgeInMonth  int      `json:"ageInMonth" validate:"required,min=1,max=120082"`
 	Description string   `json:"description" validate:"required,min=1,max=200"`
 	ImageUrls   []string `json:"imageUrls" validate:"required,dive,required,url"`
+	Private     bool     `json:"private"`
 }
 
-func SaveCat(ctx context.Context, tx *sql.Tx, cat CatInsertRequest) (int, time.Time) {
-	SQL := "INSERT INTO cats (user_email, name, race, sex, age_in_month, image_urls, description) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at"
-	id := 0
-	var createdAt time.Time
-
-	err := tx.QueryRowContext(ctx, SQL, cat.UserEmail, cat.Name, cat.Race, cat.Sex, cat.AgeInMonth, pq.Array(cat.ImageUrls), cat.Description).Scan(&id, &createdAt)
-	helper.PanicIfError(err)
-
-	createdAt.Format(time.RFC3339)
-
-	return id, createdAt
+// CatUpdateRequest is the body of a cat update. A missing private keeps the
+// cat as private as it was, for clients older than the field.
+type CatUpdateRequest struct {
+	CatInsertRequest
+	Private *bool `json:"private"`
 }
 
 type CatParam struct {
-	Id            string
-	Owned         string
-	Email         string
+	Id     string
+	Owned  string
+	UserId string
+	// ViewerId is the user the cats are listed to, the private cats of
+	// anyone else are left out. Without a viewer no private cat is listed.
+	ViewerId      string
 	AgeStr        string
 	HasMatchedStr string
 	Race          string
 	Sex           string
 	Search        string
 	Limit         string
-	Offsset       string
+	// Offsset is the legacy paging, ignored with a Cursor.
+	Offsset string
+	Cursor  string
+	// Sort is a comma separated list of createdAt, ageInMonth, name and
+	// relevance, each descending with a leading "-" (default: -createdAt).
+	Sort string
 }
 
-func GetAllCat(ctx context.Context, tx *sql.Tx, catParam CatParam) []Cat {
-	SQL := "SELECT id, name, race, sex, age_in_month, image_urls, description, hasmatched, created_at FROM cats WHERE TRUE"
+// CatPage is a page of cats in the order of the sort, with the cursors of
+// the pages around it when there are any.
+type CatPage struct {
+	Cats       []Cat
+	NextCursor string
+	PrevCursor string
+	// Limit and Offset are the paging applied, after defaults and caps.
+	Limit  int
+	Offset int
+}
 
-	params := make([]interface{}, 0)
+// maxCatLimit caps the page size, larger limits are lowered to it.
+const maxCatLimit = 100
+
+// catCursor is the position of a page boundary, the sort keys of the cat at
+// the boundary. Before marks a cursor reading the page preceding the
+// position. Cursors without a Sort were made for the default one.
+type catCursor struct {
+	Sort       string    `json:"s,omitempty"`
+	CreatedAt  time.Time `json:"t"`
+	AgeInMonth int       `json:"a,omitempty"`
+	Name       string    `json:"n,omitempty"`
+	Relevance  float64   `json:"r,omitempty"`
+	Id         string    `json:"i"`
+	Before     bool      `json:"b,omitempty"`
+}
 
-	if catParam.Owned != "" {
-		owned, err := strconv.ParseBool(catParam.Owned)
-		if err == nil {
-			if owned == true {
-				SQL += " AND user_email = $1"
-			} else {
-				SQL += " AND user_email != $1 "
-			}
+func newCatCursor(sort string, cat Cat, before bool) catCursor {
+	return catCursor{
+		Sort:       sort,
+		CreatedAt:  cat.CreatedAt,
+		AgeInMonth: cat.AgeInMonth,
+		Name:       cat.Name,
+		Relevance:  cat.relevance,
+		Id:         cat.Id,
+		Before:     before,
+	}
+}
 
-			params = append(params, catParam.Email)
-		}
+// cat is the cat at the boundary as far as the sort keys go.
+func (c catCursor) cat() Cat {
+	return Cat{Id: c.Id, Name: c.Name, AgeInMonth: c.AgeInMonth, CreatedAt: c.CreatedAt, relevance: c.Relevance}
+}
+
+func (c catCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseCatCursor(s string) (*catCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cursor %q", ErrInvalidParam, s)
 	}
 
-	if catParam.Id != "" {
-		id, err := strconv.Atoi(catParam.Id)
-		if err == nil {
-			SQL += fmt.Sprintf(" AND id = $%d", len(params)+1)
-			params = append(params, id)
-		}
+	c := &catCursor{Sort: defaultCatSort}
+	err = json.Unmarshal(b, c)
+	if err != nil || c.Id == "" {
+		return nil, fmt.Errorf("%w: cursor %q", ErrInvalidParam, s)
 	}
 
-	if race := catParam.Race; race != "" {
-		SQL += fmt.Sprintf(" AND CAST(race AS TEXT) = $%d", len(params)+1)
-		params = append(params, race)
+	return c, nil
+}
+
+// catFilter is the parsed form of CatParam shared by every CatStore.
+type catFilter struct {
+	owned      *bool
+	userId     string
+	viewerId   string
+	id         string
+	race       string
+	sex        string
+	hasMatched *bool
+	ageOp      string
+	age        int
+	search     string
+	limit      int
+	offset     int
+	cursor     *catCursor
+	sortSpec   string
+	sort       []catSortKey
+	relevance  bool
+}
+
+func (catParam CatParam) filter() (catFilter, error) {
+	f := catFilter{
+		userId:   catParam.UserId,
+		viewerId: catParam.ViewerId,
+		id:       catParam.Id,
+		race:     catParam.Race,
+		sex:      catParam.Sex,
+		search:   strings.ToLower(catParam.Search),
 	}
 
-	if sex := catParam.Sex; sex != "" {
-		SQL += fmt.Sprintf(" AND CAST(sex AS TEXT) = $%d", len(params)+1)
-		params = append(params, sex)
+	if catParam.Owned != "" {
+		owned, err := strconv.ParseBool(catParam.Owned)
+		if err == nil {
+			f.owned = &owned
+		}
 	}
 
 	if hasMatched := catParam.HasMatchedStr; hasMatched != "" {
 		match, err := strconv.ParseBool(hasMatched)
 		if err == nil {
-			SQL += fmt.Sprintf(" AND hasMatched = $%d", len(params)+1)
-			params = append(params, match)
+			f.hasMatched = &match
 		}
 	}
 
 	if ageStr := catParam.AgeStr; len(ageStr) > 1 {
-		var operator string
-		var ageValue int
-		var err error
-
 		// TODO: Update validasi
 
 		switch ageStr[0] {
-		case '>':
-			operator = ">"
-			ageValue, err = strconv.Atoi(ageStr[1:])
-			helper.PanicIfError(err)
-			SQL += fmt.Sprintf(" AND age_in_month %s $%d", operator, len(params)+1)
-			params = append(params, ageValue)
-			break
-
-		case '<':
-			operator = "<"
-			ageValue, err = strconv.Atoi(ageStr[1:])
-			helper.PanicIfError(err)
-			SQL += fmt.Sprintf(" AND age_in_month %s $%d", operator, len(params)+1)
-			params = append(params, ageValue)
-			break
-
-		case '=':
-			operator = "="
-			ageValue, err = strconv.Atoi(ageStr[1:])
-			helper.PanicIfError(err)
-
-			SQL += fmt.Sprintf(" AND age_in_month %s $%d", operator, len(params)+1)
-			params = append(params, ageValue)
-			break
+		case '>', '<', '=':
+			ageValue, err := strconv.Atoi(ageStr[1:])
+			if err != nil {
+				return f, fmt.Errorf("%w: ageInMonth %q", ErrInvalidParam, ageStr)
+			}
 
+			f.ageOp = string(ageStr[0])
+			f.age = ageValue
 		default:
 		}
 	}
 
-	if search := catParam.Search; search != "" {
-		SQL += fmt.Sprintf(" AND LOWER(name) like $%d", len(params)+1)
-		params = append(params, "%"+strings.ToLower(search)+"%")
+	limit, err := strconv.Atoi(catParam.Limit)
+	if err != nil || limit < 1 {
+		limit = 5
 	}
+	f.limit = min(limit, maxCatLimit)
 
-	limit, err := strconv.Atoi(catParam.Limit)
+	f.sortSpec = catParam.Sort
+	if f.sortSpec == "" {
+		f.sortSpec = defaultCatSort
+	}
+	f.sort, f.relevance, err = parseCatSort(f.sortSpec, f.search)
 	if err != nil {
-		limit = 5
+		return f, err
+	}
+
+	if catParam.Cursor != "" {
+		f.cursor, err = parseCatCursor(catParam.Cursor)
+		if err == nil && f.cursor.Sort != f.sortSpec {
+			err = fmt.Errorf("%w: cursor of sort %q", ErrInvalidParam, f.cursor.Sort)
+		}
+		return f, err
 	}
 
 	offset, err := strconv.Atoi(catParam.Offsset)
-	if err != nil {
+	if err != nil || offset < 0 {
 		offset = 0
 	}
+	f.offset = offset
+
+	return f, nil
+}
+
+// page cuts the limit+1 cats read in the order of the cursor into a page,
+// the extra cat tells whether there is more in that direction.
+func (f catFilter) page(cats []Cat) CatPage {
+	more := len(cats) > f.limit
+	if more {
+		cats = cats[:f.limit]
+	}
+
+	hasNext, hasPrev := more, f.offset > 0
+	if f.cursor != nil && f.cursor.Before {
+		slices.Reverse(cats)
+		hasNext, hasPrev = true, more
+	} else if f.cursor != nil {
+		hasPrev = true
+	}
+
+	page := CatPage{Cats: cats, Limit: f.limit, Offset: f.offset}
+	if len(cats) == 0 {
+		return page
+	}
+
+	if hasNext {
+		last := cats[len(cats)-1]
+		page.NextCursor = newCatCursor(f.sortSpec, last, false).String()
+	}
+	if hasPrev {
+		first := cats[0]
+		page.PrevCursor = newCatCursor(f.sortSpec, first, true).String()
+	}
+
+	return page
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns the 48 bits of milliseconds of t followed by 80 random bits
+// in Crockford's base32, ids sort by creation time and cannot be guessed.
+func newULID(t time.Time) string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(t.UnixMilli())<<16)
+	_, err := rand.Read(b[6:])
+	if err != nil {
+		panic(err)
+	}
+
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	id := make([]byte, 26)
+	for i := len(id) - 1; i >= 0; i-- {
+		id[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(id)
+}
+
+type postgresCatStore struct {
+	tx *sql.Tx
+}
+
+func (s *postgresCatStore) SaveCat(ctx context.Context, cat CatInsertRequest) (string, time.Time, error) {
+	SQL := "INSERT INTO cats (public_id, user_id, name, race, sex, age_in_month, image_urls, description, private) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING public_id, created_at"
+	id := ""
+	var createdAt time.Time
+
+	err := s.tx.QueryRowContext(ctx, SQL, newULID(time.Now()), cat.UserId, cat.Name, cat.Race, cat.Sex, cat.AgeInMonth, pq.Array(cat.ImageUrls), cat.Description, cat.Private).Scan(&id, &createdAt)
+
+	return id, createdAt, err
+}
+
+// where is the SQL condition of the filters, cursor and paging aside.
+func (f catFilter) where() (string, []interface{}) {
+	SQL := "TRUE"
+	params := make([]interface{}, 0)
+
+	if f.owned != nil {
+		if *f.owned {
+			SQL += " AND user_id = $1"
+		} else {
+			SQL += " AND user_id != $1 "
+		}
+
+		params = append(params, f.userId)
+	}
+
+	if f.viewerId != "" {
+		SQL += fmt.Sprintf(" AND (NOT private OR user_id = $%d)", len(params)+1)
+		params = append(params, f.viewerId)
+	} else {
+		SQL += " AND NOT private"
+	}
+
+	if f.id != "" {
+		SQL += fmt.Sprintf(" AND public_id = $%d", len(params)+1)
+		params = append(params, f.id)
+	}
+
+	if race := f.race; race != "" {
+		SQL += fmt.Sprintf(" AND CAST(race AS TEXT) = $%d", len(params)+1)
+		params = append(params, race)
+	}
 
-	SQL += fmt.Sprintf(" ORDER BY created_at DESC LIMIT %d OFFSET %d", limit, offset)
+	if sex := f.sex; sex != "" {
+		SQL += fmt.Sprintf(" AND CAST(sex AS TEXT) = $%d", len(params)+1)
+		params = append(params, sex)
+	}
+
+	if f.hasMatched != nil {
+		SQL += fmt.Sprintf(" AND hasMatched = $%d", len(params)+1)
+		params = append(params, *f.hasMatched)
+	}
+
+	if f.ageOp != "" {
+		SQL += fmt.Sprintf(" AND age_in_month %s $%d", f.ageOp, len(params)+1)
+		params = append(params, f.age)
+	}
+
+	if search := f.search; search != "" {
+		SQL += fmt.Sprintf(" AND LOWER(name) like $%d", len(params)+1)
+		params = append(params, "%"+search+"%")
+	}
 
-	rows, err := tx.QueryContext(ctx, SQL, params...)
-	helper.PanicIfError(err)
+	return SQL, params
+}
+
+func (s *postgresCatStore) GetAllCat(ctx context.Context, catParam CatParam) (CatPage, error) {
+	f, err := catParam.filter()
+	if err != nil {
+		return CatPage{}, err
+	}
+
+	where, params := f.where()
+
+	search, relevance := "", "0"
+	if f.relevance {
+		params = append(params, f.search)
+		search = fmt.Sprintf("$%d", len(params))
+		relevance = catSortFields["relevance"].sql(search)
+	}
+
+	SQL := "SELECT public_id, name, race, sex, age_in_month, image_urls, description, hasmatched, private, created_at, " + relevance + " FROM cats WHERE " + where
+	if f.cursor != nil {
+		var keyset string
+		keyset, params = f.keyset(search, params)
+		SQL += " AND " + keyset
+	}
+
+	SQL += fmt.Sprintf(" ORDER BY %s LIMIT %d OFFSET %d", f.orderBy(search), f.limit+1, f.offset)
+
+	rows, err := s.tx.QueryContext(ctx, SQL, params...)
+	if err != nil {
+		return CatPage{}, err
+	}
 	defer rows.Close()
 
 	cats := []Cat{}
 	for rows.Next() {
 		cat := &Cat{}
-		rows.Scan(&cat.Id, &cat.Name, &cat.Race, &cat.Sex, &cat.AgeInMonth, pq.Array(&cat.ImageUrls), &cat.Description, &cat.HasMatched, &cat.CreatedAt)
-		cat.CreatedAt.Format(time.RFC3339)
+		err := rows.Scan(&cat.Id, &cat.Name, &cat.Race, &cat.Sex, &cat.AgeInMonth, pq.Array(&cat.ImageUrls), &cat.Description, &cat.HasMatched, &cat.Private, &cat.CreatedAt, &cat.relevance)
+		if err != nil {
+			return CatPage{}, err
+		}
 
 		cats = append(cats, *cat)
 	}
 
-	return cats
+	return f.page(cats), rows.Err()
 }
 
-func GetCatById(ctx context.Context, tx *sql.Tx, Id int) (Cat, error) {
-	cat := Cat{}
-	SQL := "SELECT id, user_email, name, race, sex, age_in_month, image_urls, description, hasmatched, created_at FROM cats WHERE id = $1;"
+// CountCat counts apart from the page, a COUNT(*) OVER () in the page query
+// would read every matching row even when the page is the first few.
+func (s *postgresCatStore) CountCat(ctx context.Context, catParam CatParam, estimate bool) (int, error) {
+	f, err := catParam.filter()
+	if err != nil {
+		return 0, err
+	}
 
-	row, err := tx.QueryContext(ctx, SQL, Id)
-	helper.PanicIfError(err)
-	defer row.Close()
+	where, params := f.where()
+	if !estimate {
+		count := 0
+		err := s.tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM cats WHERE "+where, params...).Scan(&count)
 
-	if row.Next() == false {
-		return cat, errors.New("cat id is not valid")
+		return count, err
 	}
 
-	row.Scan(&cat.Id, &cat.UserEmail, &cat.Name, &cat.Race, &cat.Sex, &cat.AgeInMonth, pq.Array(&cat.ImageUrls), &cat.Description, &cat.HasMatched, &cat.CreatedAt)
+	// the row estimate of the planner, from the table statistics
+	var plan []byte
+	err = s.tx.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) SELECT 1 FROM cats WHERE "+where, params...).Scan(&plan)
+	if err != nil {
+		return 0, err
+	}
 
-	cat.CreatedAt.Format(time.RFC3339)
-	return cat, nil
+	explain := []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		}
+	}{}
+	err = json.Unmarshal(plan, &explain)
+	if err != nil || len(explain) == 0 {
+		return 0, fmt.Errorf("unexpected query plan %q: %w", plan, err)
+	}
+
+	return int(explain[0].Plan.Rows), nil
+}
+
+// catSelect reads the owner as "" for the cats a deleted account left behind.
+const catSelect = "SELECT public_id, COALESCE(user_id::TEXT, ''), name, race, sex, age_in_month, image_urls, description, hasmatched, private, created_at FROM cats"
+
+func scanCat(row rowScanner) (Cat, error) {
+	cat := Cat{}
+	err := row.Scan(&cat.Id, &cat.UserId, &cat.Name, &cat.Race, &cat.Sex, &cat.AgeInMonth, pq.Array(&cat.ImageUrls), &cat.Description, &cat.HasMatched, &cat.Private, &cat.CreatedAt)
+
+	return cat, err
 }
 
-func DestroyCat(ctx context.Context, tx *sql.Tx, id int, email string) error {
+func (s *postgresCatStore) GetCatById(ctx context.Context, id string) (Cat, error) {
+	cat, err := scanCat(s.tx.QueryRowContext(ctx, catSelect+" WHERE public_id = $1", id))
+
+	return cat, notFound(err, ErrCatNotFound)
+}
+
+func (s *postgresCatStore) GetCatByLegacyId(ctx context.Context, id int) (Cat, error) {
+	cat, err := scanCat(s.tx.QueryRowContext(ctx, catSelect+" WHERE id = $1", id))
+
+	return cat, notFound(err, ErrCatNotFound)
+}
+
+func (s *postgresCatStore) DestroyCat(ctx context.Context, id string, userId string) error {
 	status := 0
-	SQL := "DELETE FROM cats WHERE id = $1 AND user_email = $2 RETURNING id;"
+	SQL := "DELETE FROM cats WHERE public_id = $1 AND user_id = $2 RETURNING id;"
 
-	err := tx.QueryRowContext(ctx, SQL, id, email).Scan(&status)
+	err := s.tx.QueryRowContext(ctx, SQL, id, userId).Scan(&status)
 
-	return err
+	return notFound(err, ErrCatNotFound)
 }
 
-func UpdateCatWithSex(ctx context.Context, tx *sql.Tx, id int, cat CatInsertRequest) error {
-	SQL := "UPDATE cats SET name = $1, race = $2, sex = $3, age_in_month = $4, image_urls = $5, description = $6 WHERE id = $7 AND user_email = $8 RETURNING id"
+func (s *postgresCatStore) DestroyCatById(ctx context.Context, id string) error {
 	status := 0
+	SQL := "DELETE FROM cats WHERE public_id = $1 RETURNING id;"
 
-	err := tx.QueryRowContext(ctx, SQL, cat.Name, cat.Race, cat.Sex, cat.AgeInMonth, pq.Array(cat.ImageUrls), cat.Description, id, cat.UserEmail).Scan(&status)
+	err := s.tx.QueryRowContext(ctx, SQL, id).Scan(&status)
 
-	return err
+	return notFound(err, ErrCatNotFound)
+}
+
+func (s *postgresCatStore) DestroyUserCats(ctx context.Context, userId string) (int, error) {
+	SQL := `DELETE FROM cats c WHERE user_id = $1 AND NOT EXISTS (
+				SELECT 1 FROM matches m
+				WHERE m.status = 'approved' AND (m.issuer_cat_id = c.id OR m.receiver_cat_id = c.id))`
+
+	result, err := s.tx.ExecContext(ctx, SQL, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+
+	return int(count), err
 }
 
-func UpdateCatWithoutSex(ctx context.Context, tx *sql.Tx, id int, cat CatInsertRequest) error {
-	SQL := "UPDATE cats SET name = $1, race = $2, age_in_month = $3, image_urls = $3, description = $5 WHERE id = $6 AND user_email = $7 RETURNING id"
+func (s *postgresCatStore) DetachUserCats(ctx context.Context, userId string) (int, error) {
+	SQL := "UPDATE cats SET user_id = NULL, private = TRUE WHERE user_id = $1"
+
+	result, err := s.tx.ExecContext(ctx, SQL, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+
+	return int(count), err
+}
+
+func (s *postgresCatStore) UpdateCatWithSex(ctx context.Context, id string, cat CatInsertRequest) error {
+	SQL := "UPDATE cats SET name = $1, race = $2, sex = $3, age_in_month = $4, image_urls = $5, description = $6, private = $7 WHERE public_id = $8 AND user_id = $9 RETURNING id"
 	status := 0
 
-	err := tx.QueryRowContext(ctx, SQL, cat.Name, cat.Race, cat.AgeInMonth, pq.Array(cat.ImageUrls), cat.Description, id, cat.UserEmail).Scan(&status)
+	err := s.tx.QueryRowContext(ctx, SQL, cat.Name, cat.Race, cat.Sex, cat.AgeInMonth, pq.Array(cat.ImageUrls), cat.Description, cat.Private, id, cat.UserId).Scan(&status)
 
-	return err
+	return notFound(err, ErrCatNotFound)
+}
+
+func (s *postgresCatStore) UpdateCatWithoutSex(ctx context.Context, id string, cat CatInsertRequest) error {
+	SQL := "UPDATE cats SET name = $1, race = $2, age_in_month = $3, image_urls = $4, description = $5, private = $6 WHERE public_id = $7 AND user_id = $8 RETURNING id"
+	status := 0
+
+	err := s.tx.QueryRowContext(ctx, SQL, cat.Name, cat.Race, cat.AgeInMonth, pq.Array(cat.ImageUrls), cat.Description, cat.Private, id, cat.UserId).Scan(&status)
+
+	return notFound(err, ErrCatNotFound)
 }
 
 // update property hasMatched
-func UpdateStatusCat(ctx context.Context, tx *sql.Tx, idCat1 string, idCat2 string) {
-	SQL := "UPDATE cats SET hasMatched = TRUE WHERE id IN ($1, $2)"
+func (s *postgresCatStore) UpdateStatusCat(ctx context.Context, idCat1 string, idCat2 string) error {
+	SQL := "UPDATE cats SET hasMatched = TRUE WHERE public_id IN ($1, $2)"
 
-	_, err := tx.ExecContext(ctx, SQL, idCat1, idCat2)
-	helper.PanicIfError(err)
+	_, err := s.tx.ExecContext(ctx, SQL, idCat1, idCat2)
+
+	return err
 }
@@ -0,0 +1,241 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testCats share names, ages and creation times so that every sort has
+// ties only the id breaks.
+func testCats() *memoryCatStore {
+	t0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	cats := []Cat{
+		{Id: "a1", Name: "Bella", AgeInMonth: 2, CreatedAt: t0},
+		{Id: "a2", Name: "Bella", AgeInMonth: 2, CreatedAt: t0},
+		{Id: "a3", Name: "Coco", AgeInMonth: 5, CreatedAt: t0.Add(time.Hour)},
+		{Id: "a4", Name: "Abby", AgeInMonth: 5, CreatedAt: t0.Add(time.Hour)},
+		{Id: "a5", Name: "Coco", AgeInMonth: 2, CreatedAt: t0.Add(2 * time.Hour)},
+		{Id: "a6", Name: "Dora", AgeInMonth: 9, CreatedAt: t0.Add(2 * time.Hour)},
+	}
+
+	state := &memoryState{cats: map[string]Cat{}}
+	for _, cat := range cats {
+		state.cats[cat.Id] = cat
+	}
+
+	return &memoryCatStore{state: state}
+}
+
+func catIds(cats []Cat) []string {
+	ids := []string{}
+	for _, cat := range cats {
+		ids = append(ids, cat.Id)
+	}
+
+	return ids
+}
+
+func TestCatCursorPaging(t *testing.T) {
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"a6", "a5", "a4", "a3", "a2", "a1"}},
+		{"-createdAt", []string{"a6", "a5", "a4", "a3", "a2", "a1"}},
+		{"createdAt", []string{"a1", "a2", "a3", "a4", "a5", "a6"}},
+		{"ageInMonth,-name", []string{"a5", "a2", "a1", "a3", "a4", "a6"}},
+		{"-ageInMonth,name", []string{"a6", "a4", "a3", "a1", "a2", "a5"}},
+		{"name,-createdAt", []string{"a4", "a2", "a1", "a5", "a3", "a6"}},
+	}
+
+	ctx := context.Background()
+	store := testCats()
+
+	for _, tt := range tests {
+		// forward, page after page
+		pages := []CatPage{}
+		ids := []string{}
+		cursor := ""
+		for i := 0; i < len(tt.want); i++ {
+			page, err := store.GetAllCat(ctx, CatParam{Sort: tt.sort, Limit: "4", Cursor: cursor})
+			if err != nil {
+				t.Fatalf("sort %q: %v", tt.sort, err)
+			}
+
+			pages = append(pages, page)
+			ids = append(ids, catIds(page.Cats)...)
+			if len(pages) == 1 && page.PrevCursor != "" {
+				t.Errorf("sort %q: first page has a previous cursor", tt.sort)
+			}
+
+			cursor = page.NextCursor
+			if cursor == "" {
+				break
+			}
+		}
+
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("sort %q forward: got %v, want %v", tt.sort, ids, tt.want)
+		}
+		if len(pages) != 2 {
+			t.Fatalf("sort %q: got %d pages of 4, want 2", tt.sort, len(pages))
+		}
+
+		// backward from the last page lands on the first one again
+		last := pages[len(pages)-1]
+		page, err := store.GetAllCat(ctx, CatParam{Sort: tt.sort, Limit: "4", Cursor: last.PrevCursor})
+		if err != nil {
+			t.Fatalf("sort %q: %v", tt.sort, err)
+		}
+
+		if got := catIds(page.Cats); !reflect.DeepEqual(got, tt.want[:4]) {
+			t.Errorf("sort %q backward: got %v, want %v", tt.sort, got, tt.want[:4])
+		}
+		if page.PrevCursor != "" || page.NextCursor == "" {
+			t.Errorf("sort %q backward: got prev %q and next %q, want only a next cursor", tt.sort, page.PrevCursor, page.NextCursor)
+		}
+
+		// a page before a cursor in the middle stops at the start
+		middle := pages[0].Cats[2]
+		before := newCatCursor(sortOf(tt.sort), middle, true).String()
+		page, err = store.GetAllCat(ctx, CatParam{Sort: tt.sort, Limit: "4", Cursor: before})
+		if err != nil {
+			t.Fatalf("sort %q: %v", tt.sort, err)
+		}
+
+		if got := catIds(page.Cats); !reflect.DeepEqual(got, tt.want[:2]) || page.PrevCursor != "" {
+			t.Errorf("sort %q before %s: got %v and prev %q, want %v", tt.sort, middle.Id, got, page.PrevCursor, tt.want[:2])
+		}
+	}
+}
+
+func sortOf(sort string) string {
+	if sort == "" {
+		return defaultCatSort
+	}
+
+	return sort
+}
+
+func TestCatKeyset(t *testing.T) {
+	boundary := Cat{Id: "a3", Name: "Coco", AgeInMonth: 5, CreatedAt: time.Date(2026, 10, 1, 1, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		sort   string
+		before bool
+		sql    string
+		params []interface{}
+	}{
+		{
+			sort:   "-createdAt",
+			sql:    "(created_at, public_id) < ($2, $3)",
+			params: []interface{}{"owner", boundary.CreatedAt, "a3"},
+		},
+		{
+			sort:   "-createdAt",
+			before: true,
+			sql:    "(created_at, public_id) > ($2, $3)",
+			params: []interface{}{"owner", boundary.CreatedAt, "a3"},
+		},
+		{
+			sort:   "ageInMonth,name",
+			sql:    "(age_in_month, name, public_id) > ($2, $3, $4)",
+			params: []interface{}{"owner", 5, "Coco", "a3"},
+		},
+		{
+			sort:   "ageInMonth,-name",
+			sql:    "((age_in_month > $2) OR (age_in_month = $2 AND name < $3) OR (age_in_month = $2 AND name = $3 AND public_id < $4))",
+			params: []interface{}{"owner", 5, "Coco", "a3"},
+		},
+		{
+			sort:   "ageInMonth,-name",
+			before: true,
+			sql:    "((age_in_month < $2) OR (age_in_month = $2 AND name > $3) OR (age_in_month = $2 AND name = $3 AND public_id > $4))",
+			params: []interface{}{"owner", 5, "Coco", "a3"},
+		},
+	}
+
+	for _, tt := range tests {
+		sort, _, err := parseCatSort(tt.sort, "")
+		if err != nil {
+			t.Fatalf("sort %q: %v", tt.sort, err)
+		}
+
+		cursor := newCatCursor(tt.sort, boundary, tt.before)
+		f := catFilter{sort: sort, cursor: &cursor}
+
+		sql, params := f.keyset("", []interface{}{"owner"})
+		if sql != tt.sql {
+			t.Errorf("sort %q before %t: got\n%s\nwant\n%s", tt.sort, tt.before, sql, tt.sql)
+		}
+		if !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("sort %q before %t: got params %v, want %v", tt.sort, tt.before, params, tt.params)
+		}
+	}
+}
+
+func TestCatCursorRejected(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	valid := newCatCursor(defaultCatSort, Cat{Id: "a1", CreatedAt: time.Now()}, false).String()
+
+	tests := []struct {
+		name   string
+		sort   string
+		cursor string
+	}{
+		{"not base64", "", "!!!"},
+		{"not JSON", "", encode("cats")},
+		{"no id", "", encode(`{"t":"2026-10-01T00:00:00Z"}`)},
+		{"bad time", "", encode(`{"t":"yesterday","i":"a1"}`)},
+		{"truncated", "", valid[:len(valid)-4]},
+		{"other sort", "name", valid},
+		{"sort edited", "", encode(`{"s":"name","t":"2026-10-01T00:00:00Z","i":"a1"}`)},
+		{"sortless cursor with a sort", "name", encode(`{"t":"2026-10-01T00:00:00Z","i":"a1"}`)},
+	}
+
+	for _, tt := range tests {
+		_, err := CatParam{Sort: tt.sort, Cursor: tt.cursor}.filter()
+		if !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s: got %v, want ErrInvalidParam", tt.name, err)
+		}
+	}
+
+	_, err := CatParam{Cursor: valid}.filter()
+	if err != nil {
+		t.Errorf("valid cursor: %v", err)
+	}
+}
+
+func TestCatPageOffset(t *testing.T) {
+	store := testCats()
+
+	page, err := store.GetAllCat(context.Background(), CatParam{Sort: "createdAt", Limit: "2", Offsset: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := catIds(page.Cats); !slices.Equal(got, []string{"a3", "a4"}) {
+		t.Errorf("got %v, want [a3 a4]", got)
+	}
+	if page.PrevCursor == "" || page.NextCursor == "" {
+		t.Errorf("got prev %q and next %q, want both", page.PrevCursor, page.NextCursor)
+	}
+	if page.Limit != 2 || page.Offset != 2 {
+		t.Errorf("got limit %d and offset %d, want 2 and 2", page.Limit, page.Offset)
+	}
+
+	page, err = store.GetAllCat(context.Background(), CatParam{Limit: "1000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Limit != maxCatLimit || page.NextCursor != "" {
+		t.Errorf("limit 1000: got limit %d and next %q, want %d and none", page.Limit, page.NextCursor, maxCatLimit)
+	}
+}
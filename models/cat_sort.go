@@ -0,0 +1,202 @@
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// defaultCatSort lists the newest cats first.
+const defaultCatSort = "-createdAt"
+
+// catSortField is a field cats can be sorted by. sql is its expression,
+// given the placeholder of the search for the relevance.
+type catSortField struct {
+	sql     func(search string) string
+	compare func(a Cat, b Cat) int
+	value   func(cat Cat) any
+}
+
+func column(name string) func(string) string {
+	return func(string) string { return name }
+}
+
+// catSortFields whitelists the fields of the sort parameter, the SQL of a
+// sort only ever comes from here.
+var catSortFields = map[string]catSortField{
+	"createdAt": {
+		sql:     column("created_at"),
+		compare: func(a Cat, b Cat) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		value:   func(cat Cat) any { return cat.CreatedAt },
+	},
+	"ageInMonth": {
+		sql:     column("age_in_month"),
+		compare: func(a Cat, b Cat) int { return cmp.Compare(a.AgeInMonth, b.AgeInMonth) },
+		value:   func(cat Cat) any { return cat.AgeInMonth },
+	},
+	"name": {
+		sql:     column("name"),
+		compare: func(a Cat, b Cat) int { return strings.Compare(a.Name, b.Name) },
+		value:   func(cat Cat) any { return cat.Name },
+	},
+	"relevance": {
+		sql:     func(search string) string { return "similarity(LOWER(name), " + search + ")" },
+		compare: func(a Cat, b Cat) int { return cmp.Compare(a.relevance, b.relevance) },
+		value:   func(cat Cat) any { return cat.relevance },
+	},
+}
+
+// catIdSort breaks the ties of every sort so pages neither skip nor repeat
+// cats.
+var catIdSort = catSortField{
+	sql:     column("public_id"),
+	compare: func(a Cat, b Cat) int { return strings.Compare(a.Id, b.Id) },
+	value:   func(cat Cat) any { return cat.Id },
+}
+
+type catSortKey struct {
+	field catSortField
+	desc  bool
+}
+
+// parseCatSort reads a comma separated list of fields, each descending with
+// a leading "-", e.g. "-ageInMonth,name". The relevance needs a search.
+func parseCatSort(sort string, search string) ([]catSortKey, bool, error) {
+	keys := []catSortKey{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(sort, ",") {
+		name = strings.TrimSpace(name)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+
+		field, ok := catSortFields[name]
+		if !ok || seen[name] || name == "relevance" && search == "" {
+			return nil, false, fmt.Errorf("%w: sort %q", ErrInvalidParam, sort)
+		}
+
+		seen[name] = true
+		keys = append(keys, catSortKey{field: field, desc: desc})
+	}
+
+	keys = append(keys, catSortKey{field: catIdSort, desc: keys[len(keys)-1].desc})
+
+	return keys, seen["relevance"], nil
+}
+
+// compare orders a and b as a page does.
+func (f catFilter) compare(a Cat, b Cat) int {
+	for _, key := range f.sort {
+		order := key.field.compare(a, b)
+		if key.desc {
+			order = -order
+		}
+		if order != 0 {
+			return order
+		}
+	}
+
+	return 0
+}
+
+// keeps reports whether cat is strictly on the side of the cursor position
+// the cursor reads, after it or before it.
+func (f catFilter) keeps(cat Cat) bool {
+	order := f.compare(cat, f.cursor.cat())
+	if f.cursor.Before {
+		return order < 0
+	}
+
+	return order > 0
+}
+
+// orderBy is the SQL ordering of a page, reversed to read the page before a
+// cursor.
+func (f catFilter) orderBy(search string) string {
+	before := f.cursor != nil && f.cursor.Before
+
+	columns := []string{}
+	for _, key := range f.sort {
+		column := key.field.sql(search)
+		if key.desc != before {
+			column += " DESC"
+		}
+		columns = append(columns, column)
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+// keyset is the SQL condition of the cats past the cursor, a row comparison
+// the indexes serve when every key has the same direction.
+func (f catFilter) keyset(search string, params []interface{}) (string, []interface{}) {
+	boundary := f.cursor.cat()
+	uniform := true
+
+	columns, placeholders, ops := []string{}, []string{}, []string{}
+	for _, key := range f.sort {
+		params = append(params, key.field.value(boundary))
+		columns = append(columns, key.field.sql(search))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(params)))
+
+		op := ">"
+		if key.desc != f.cursor.Before {
+			op = "<"
+		}
+		ops = append(ops, op)
+		uniform = uniform && key.desc == f.sort[0].desc
+	}
+
+	if uniform {
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), ops[0], strings.Join(placeholders, ", ")), params
+	}
+
+	// a past the cursor, or a equal and b past it, and so on
+	terms := []string{}
+	for i := range columns {
+		conditions := []string{}
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, columns[j]+" = "+placeholders[j])
+		}
+		conditions = append(conditions, columns[i]+" "+ops[i]+" "+placeholders[i])
+		terms = append(terms, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return "(" + strings.Join(terms, " OR ") + ")", params
+}
+
+// similarity mirrors the pg_trgm function of the same name, the shared
+// trigrams of a and b over all their trigrams.
+func similarity(a string, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range ta {
+		if tb[trigram] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams are those of every alphanumeric word of s, lowercased and padded
+// with two spaces before and one after.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+
+	return set
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseCatSort(t *testing.T) {
+	tests := []struct {
+		sort    string
+		search  string
+		orderBy string
+		before  string
+		err     bool
+	}{
+		{sort: "-createdAt", orderBy: "created_at DESC, public_id DESC", before: "created_at, public_id"},
+		{sort: "name", orderBy: "name, public_id", before: "name DESC, public_id DESC"},
+		{sort: "-ageInMonth,name", orderBy: "age_in_month DESC, name, public_id", before: "age_in_month, name DESC, public_id DESC"},
+		{sort: "name,-ageInMonth", orderBy: "name, age_in_month DESC, public_id DESC", before: "name DESC, age_in_month, public_id"},
+		{sort: " name , -createdAt ", orderBy: "name, created_at DESC, public_id DESC", before: "name DESC, created_at, public_id"},
+		{sort: "-relevance", search: "tom", orderBy: "similarity(LOWER(name), $9) DESC, public_id DESC", before: "similarity(LOWER(name), $9), public_id"},
+		{sort: "relevance,-createdAt", search: "tom", orderBy: "similarity(LOWER(name), $9), created_at DESC, public_id DESC", before: "similarity(LOWER(name), $9) DESC, created_at, public_id"},
+		{sort: "", err: true},
+		{sort: "color", err: true},
+		{sort: "Name", err: true},
+		{sort: "public_id", err: true},
+		{sort: "name,", err: true},
+		{sort: "--name", err: true},
+		{sort: "+name", err: true},
+		{sort: "name,name", err: true},
+		{sort: "name,-name", err: true},
+		{sort: "-createdAt,ageInMonth,createdAt", err: true},
+		{sort: "relevance", err: true},
+		{sort: "-relevance", err: true},
+		{sort: "name,relevance", err: true},
+	}
+
+	for _, tt := range tests {
+		sort, relevance, err := parseCatSort(tt.sort, tt.search)
+		if tt.err {
+			if !errors.Is(err, ErrInvalidParam) {
+				t.Errorf("sort %q: got %v, want ErrInvalidParam", tt.sort, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("sort %q: %v", tt.sort, err)
+			continue
+		}
+
+		if relevance != strings.Contains(tt.sort, "relevance") {
+			t.Errorf("sort %q: got relevance %t", tt.sort, relevance)
+		}
+
+		f := catFilter{sort: sort}
+		if got := f.orderBy("$9"); got != tt.orderBy {
+			t.Errorf("sort %q: got order %q, want %q", tt.sort, got, tt.orderBy)
+		}
+
+		f.cursor = &catCursor{Id: "a1", Before: true}
+		if got := f.orderBy("$9"); got != tt.before {
+			t.Errorf("sort %q before a cursor: got order %q, want %q", tt.sort, got, tt.before)
+		}
+	}
+}
+
+// TestCatSortSQL checks that what users send ends up in the parameters of
+// the query, never in its text.
+func TestCatSortSQL(t *testing.T) {
+	hostile := []string{
+		"name; DROP TABLE cats",
+		"name DESC",
+		"(SELECT password FROM users)",
+		"created_at",
+		"name\x00",
+		"-name--",
+		"relevance'",
+	}
+
+	for _, sort := range hostile {
+		_, err := CatParam{Search: "tom", Sort: sort}.filter()
+		if !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("sort %q: got %v, want ErrInvalidParam", sort, err)
+		}
+	}
+
+	search := "tom'); DROP TABLE cats; --"
+	f, err := CatParam{Search: search, Sort: "-relevance,name"}.filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.cursor = &catCursor{Sort: "-relevance,name", Name: "'); DROP TABLE users; --", Id: "' OR TRUE --"}
+	where, params := f.where()
+	keyset, params := f.keyset("$9", params)
+
+	for _, sql := range []string{where, keyset, f.orderBy("$9")} {
+		if strings.Contains(sql, "DROP") || strings.Contains(sql, "'") {
+			t.Errorf("user text in the SQL %q", sql)
+		}
+	}
+
+	found := 0
+	for _, param := range params {
+		s, _ := param.(string)
+		if strings.Contains(s, "DROP") || strings.Contains(s, "'") {
+			found++
+		}
+	}
+	if found != 3 {
+		t.Errorf("got %d parameters with the user text, want 3: %v", found, params)
+	}
+}
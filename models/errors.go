@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrEmailTaken      = errors.New("email has taken")
+	ErrCatNotFound     = errors.New("cat not found")
+	ErrMatchNotFound   = errors.New("match not found")
+	ErrMatchNotPending = errors.New("match is no longer pending")
+	ErrInvalidParam    = errors.New("invalid parameter")
+	ErrTokenNotFound   = errors.New("token not found")
+	ErrTokenReused     = errors.New("token already used")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// notFound maps a missing row, or an id postgres cannot even parse, to err.
+func notFound(got error, err error) error {
+	var pqErr *pq.Error
+	if errors.Is(got, sql.ErrNoRows) || errors.As(got, &pqErr) && pqErr.Code == "22P02" {
+		return err
+	}
+
+	return got
+}
+
+// conflict maps a unique constraint violation to err.
+func conflict(got error, err error) error {
+	var pqErr *pq.Error
+	if errors.As(got, &pqErr) && pqErr.Code == "23505" {
+		return err
+	}
+
+	return got
+}
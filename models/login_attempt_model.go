@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	AttemptScopeAccount = "account"
+	AttemptScopeIP      = "ip"
+)
+
+// LoginAttempt counts the consecutive failed logins of an account or of a
+// client address. LockedUntil is when the next login may be tried.
+type LoginAttempt struct {
+	Scope         string
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+type postgresLoginAttemptStore struct {
+	tx *sql.Tx
+}
+
+func (s *postgresLoginAttemptStore) GetLoginAttempt(ctx context.Context, scope string, key string) (LoginAttempt, error) {
+	attempt := LoginAttempt{Scope: scope, Key: key}
+	SQL := `SELECT failures, last_failure_at, locked_until FROM login_attempts
+			WHERE scope = $1 AND attempt_key = $2 FOR UPDATE`
+
+	err := s.tx.QueryRowContext(ctx, SQL, scope, key).Scan(&attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return attempt, nil
+	}
+
+	return attempt, err
+}
+
+func (s *postgresLoginAttemptStore) AddLoginFailure(ctx context.Context, scope string, key string, now time.Time, since time.Time, lockout int) (LoginAttempt, error) {
+	attempt := LoginAttempt{Scope: scope, Key: key}
+	SQL := `INSERT INTO login_attempts (scope, attempt_key, failures, last_failure_at, locked_until)
+			VALUES ($1, $2, 1, $3, $3)
+			ON CONFLICT (scope, attempt_key) DO UPDATE
+			SET failures = CASE
+					WHEN login_attempts.last_failure_at < $4 OR ($5 > 0 AND login_attempts.failures >= $5) THEN 1
+					ELSE login_attempts.failures + 1
+				END,
+				last_failure_at = EXCLUDED.last_failure_at
+			RETURNING failures, last_failure_at, locked_until`
+
+	err := s.tx.QueryRowContext(ctx, SQL, scope, key, now, since, lockout).Scan(&attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+
+	return attempt, err
+}
+
+func (s *postgresLoginAttemptStore) LockLoginAttempt(ctx context.Context, scope string, key string, until time.Time) error {
+	SQL := "UPDATE login_attempts SET locked_until = $3 WHERE scope = $1 AND attempt_key = $2"
+
+	_, err := s.tx.ExecContext(ctx, SQL, scope, key, until)
+
+	return err
+}
+
+func (s *postgresLoginAttemptStore) ResetLoginAttempt(ctx context.Context, scope string, key string) error {
+	SQL := "DELETE FROM login_attempts WHERE scope = $1 AND attempt_key = $2"
+
+	_, err := s.tx.ExecContext(ctx, SQL, scope, key)
+
+	return err
+}
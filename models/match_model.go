@@ -3,11 +3,9 @@ package models
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
-	"log"
 	"time"
 
-	"github.com/malikfajr/cats-social/helper"
+	"github.com/lib/pq"
 )
 
 type ApproveRemoveRequest struct {
@@ -20,11 +18,6 @@ type Issuer struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
-func (d Issuer) toJson() ([]byte, error) {
-	b, err := json.Marshal(d)
-	return b, err
-}
-
 type CatDetail struct {
 	Id          string    `json:"id"`
 	Name        string    `json:"name"`
@@ -37,16 +30,11 @@ type CatDetail struct {
 	CreatedAt   time.Time `json:"createdAt"`
 }
 
-func (d CatDetail) toJson() ([]byte, error) {
-	b, err := json.Marshal(d)
-	return b, err
-}
-
 type Match struct {
 	Id             string    `json:"id"`
 	IssuedBy       Issuer    `json:"issuedBy"`
 	MatchCatDetail CatDetail `json:"matchCatDetail"`
-	MatchUserEmail string    `json:"-"`
+	MatchUserId    string    `json:"-"`
 	Status         string    `json:"-"`
 	UserCatDetail  CatDetail `json:"userCatDetail"`
 	Message        string    `json:"message"`
@@ -54,154 +42,164 @@ type Match struct {
 }
 
 type MatchInsertRequest struct {
+	IssuerId   string `json:"-"`
 	MatchCatId string `json:"matchCatId" validate:"required"`
 	UserCatId  string `json:"userCatId" validate:"required"`
 	Message    string `json:"message" validate:"required,min=5,max=120"`
 }
 
-func NewMatch(ctx context.Context, tx *sql.Tx, match Match) (string, error) {
-	var id string = ""
-	SQL := "INSERT INTO matches (status, match_user_email, issued_by, match_cat_detail, user_cat_detail, message) VALUES ('pending', $1, $2, $3, $4, $5) RETURNING id"
+// matchSelect assembles a Match from the live issuer and cat rows. A deleted
+// account leaves its approved matches without a user, read as "".
+const matchSelect = `SELECT m.id, COALESCE(m.match_user_id::TEXT, ''), m.status, m.message, m.created_at,
+		COALESCE(u.name, ''), COALESCE(u.email, ''),
+		rc.public_id, rc.name, rc.race, rc.sex, rc.description, rc.age_in_month, rc.image_urls, rc.hasmatched, rc.created_at,
+		ic.public_id, ic.name, ic.race, ic.sex, ic.description, ic.age_in_month, ic.image_urls, ic.hasmatched, ic.created_at
+	FROM matches m
+	LEFT JOIN users u ON u.id = m.issuer_id
+	JOIN cats rc ON rc.id = m.receiver_cat_id
+	JOIN cats ic ON ic.id = m.issuer_cat_id`
 
-	issuedBy, _ := match.IssuedBy.toJson()
-	matchCat, _ := match.MatchCatDetail.toJson()
-	userCat, _ := match.UserCatDetail.toJson()
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMatch(row rowScanner) (Match, error) {
+	match := Match{}
+	rc := &match.MatchCatDetail
+	ic := &match.UserCatDetail
 
-	err := tx.QueryRowContext(ctx, SQL, match.MatchUserEmail, string(issuedBy), string(matchCat), string(userCat), match.Message).Scan(&id)
+	err := row.Scan(&match.Id, &match.MatchUserId, &match.Status, &match.Message, &match.CreatedAt,
+		&match.IssuedBy.Name, &match.IssuedBy.Email,
+		&rc.Id, &rc.Name, &rc.Race, &rc.Sex, &rc.Description, &rc.AgeInMonth, pq.Array(&rc.ImageUrls), &rc.HasMatched, &rc.CreatedAt,
+		&ic.Id, &ic.Name, &ic.Race, &ic.Sex, &ic.Description, &ic.AgeInMonth, pq.Array(&ic.ImageUrls), &ic.HasMatched, &ic.CreatedAt)
+	match.IssuedBy.CreatedAt = match.CreatedAt
 
-	helper.PanicIfError(err)
+	return match, err
+}
 
-	return id, err
+type postgresMatchStore struct {
+	tx *sql.Tx
 }
 
-func CrossCheckMatchCatId(ctx context.Context, tx *sql.Tx, matchCatId string, userCatId string) int {
+func (s *postgresMatchStore) NewMatch(ctx context.Context, match MatchInsertRequest) (string, time.Time, error) {
+	var id string = ""
+	var createdAt time.Time
+	SQL := `INSERT INTO matches (status, match_user_id, issuer_id, issuer_cat_id, receiver_cat_id, message)
+			SELECT 'pending', user_id, $1, (SELECT id FROM cats WHERE public_id = $2), id, $4 FROM cats WHERE public_id = $3
+			RETURNING id, created_at`
+
+	err := s.tx.QueryRowContext(ctx, SQL, match.IssuerId, match.UserCatId, match.MatchCatId, match.Message).Scan(&id, &createdAt)
+
+	return id, createdAt, notFound(err, ErrCatNotFound)
+}
+
+func (s *postgresMatchStore) CrossCheckMatchCatId(ctx context.Context, matchCatId string, userCatId string) (int, error) {
 	count := 0
-	SQL := "SELECT COUNT(*) FROM matches WHERE match_cat_detail->>'id' = $1 AND user_cat_detail->>'id' = $2"
+	SQL := `SELECT COUNT(*) FROM matches m
+			JOIN cats rc ON rc.id = m.receiver_cat_id
+			JOIN cats ic ON ic.id = m.issuer_cat_id
+			WHERE rc.public_id = $1 AND ic.public_id = $2`
 
-	err := tx.QueryRowContext(ctx, SQL, matchCatId, userCatId).Scan(&count)
-	helper.PanicIfError(err)
+	err := s.tx.QueryRowContext(ctx, SQL, matchCatId, userCatId).Scan(&count)
 
-	return count
+	return count, err
 }
 
-func GetAllMatch(ctx context.Context, tx *sql.Tx, email string) ([]Match, error) {
+func (s *postgresMatchStore) GetAllMatch(ctx context.Context, userId string) ([]Match, error) {
 	matches := []Match{}
-	SQL := "SELECT id, issued_by, match_cat_detail, user_cat_detail, message, created_at FROM matches WHERE issued_by->>'email' = $1 OR match_user_email = $2 AND status = 'pending'"
+	SQL := matchSelect + " WHERE m.issuer_id = $1 OR m.match_user_id = $1 AND m.status = 'pending' ORDER BY m.created_at DESC"
 
-	rows, err := tx.QueryContext(ctx, SQL, email, email)
+	rows, err := s.tx.QueryContext(ctx, SQL, userId)
 	if err != nil {
 		return matches, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		match := &Match{}
-		var issuedByStr, matchCatDetailStr, userCatDetailStr string
-
-		err := rows.Scan(&match.Id, &issuedByStr, &matchCatDetailStr, &userCatDetailStr, &match.Message, &match.CreatedAt)
+		match, err := scanMatch(rows)
 		if err != nil {
-			log.Println("Error scanning row: ", err)
+			return matches, err
 		}
 
-		// text to json/struct
-		err = json.Unmarshal([]byte(issuedByStr), &match.IssuedBy)
-		if err != nil {
-			log.Println("Error unmarshalling IssuedBy JSON:", err)
-			continue
-		}
+		matches = append(matches, match)
+	}
 
-		err = json.Unmarshal([]byte(matchCatDetailStr), &match.MatchCatDetail)
-		if err != nil {
-			log.Println("Error unmarshalling MatchCatDetail JSON:", err)
-			continue
-		}
+	return matches, rows.Err()
+}
 
-		err = json.Unmarshal([]byte(userCatDetailStr), &match.UserCatDetail)
-		if err != nil {
-			log.Println("Error unmarshalling UserCatDetail JSON:", err)
-			continue
-		}
+func (s *postgresMatchStore) DeleteMatch(ctx context.Context, id string) (string, string, error) {
+	var idStr, status, issuerId string
+	SQL := "DELETE FROM matches WHERE id = $1 RETURNING id, status, COALESCE(issuer_id::TEXT, '')"
 
-		matches = append(matches, *match)
-	}
+	err := s.tx.QueryRowContext(ctx, SQL, id).Scan(&idStr, &status, &issuerId)
 
-	return matches, nil
+	return status, issuerId, notFound(err, ErrMatchNotFound)
 }
 
-func DeleteMatch(ctx context.Context, tx *sql.Tx, id string) (string, string, error) {
-	var idStr, status, email string
-	SQL := "DELETE FROM matches WHERE id = $1 RETURNING id, status, issued_by->>'email'"
+func (s *postgresMatchStore) CancelUserMatches(ctx context.Context, userId string) (int, error) {
+	SQL := "DELETE FROM matches WHERE status = 'pending' AND (issuer_id = $1 OR match_user_id = $1)"
 
-	err := tx.QueryRowContext(ctx, SQL, id).Scan(&idStr, &status, &email)
+	result, err := s.tx.ExecContext(ctx, SQL, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
 
-	return status, email, err
+	return int(count), err
 }
 
-func ApproveMatch(ctx context.Context, tx *sql.Tx, matchId string) {
-	SQL := `UPDATE matches 
-				SET status = 'approved', 
-					match_cat_detail=jsonb_set(match_cat_detail, '{hasMatched}', 'true'), 
-					user_cat_detail=jsonb_set(user_cat_detail, '{hasMatched}', 'true') 
-			WHERE id = $1`
+func (s *postgresMatchStore) DetachUserMatches(ctx context.Context, userId string) error {
+	SQL := `UPDATE matches SET
+				issuer_id = NULLIF(issuer_id, $1),
+				match_user_id = NULLIF(match_user_id, $1)
+			WHERE issuer_id = $1 OR match_user_id = $1`
 
-	tx.QueryRowContext(ctx, SQL, matchId).Scan()
+	_, err := s.tx.ExecContext(ctx, SQL, userId)
+
+	return err
 }
 
-func RejectMatch(ctx context.Context, tx *sql.Tx, matchId string) {
-	SQL := "UPDATE matches SET status = 'reject' WHERE id = $1"
+func (s *postgresMatchStore) ApproveMatch(ctx context.Context, matchId string) error {
+	SQL := "UPDATE matches SET status = 'approved' WHERE id = $1 AND status = 'pending' RETURNING id"
 
-	tx.QueryRowContext(ctx, SQL, matchId).Scan()
-}
+	err := s.tx.QueryRowContext(ctx, SQL, matchId).Scan(&matchId)
 
-func RejectOtherMatch(ctx context.Context, tx *sql.Tx, catId string, matchId string) {
-	SQL1 := `UPDATE matches 
-				SET status = 'reject', match_cat_detail=jsonb_set(match_cat_detail, '{hasMatched}', 'true')
-			WHERE id != $1 AND match_cat_detail->>'id' = $2 AND status != 'approved';`
+	return notFound(err, ErrMatchNotPending)
+}
 
-	SQL2 := `UPDATE matches 
-				SET status = 'reject', user_cat_detail=jsonb_set(user_cat_detail, '{hasMatched}', 'true')
-			WHERE id != $1 AND user_cat_detail->>'id' = $2 AND status != 'approved';`
+func (s *postgresMatchStore) RejectMatch(ctx context.Context, matchId string) error {
+	SQL := "UPDATE matches SET status = 'reject' WHERE id = $1 AND status = 'pending' RETURNING id"
 
-	_, err := tx.ExecContext(ctx, SQL1, matchId, catId)
-	helper.PanicIfError(err)
+	err := s.tx.QueryRowContext(ctx, SQL, matchId).Scan(&matchId)
 
-	_, err = tx.ExecContext(ctx, SQL2, matchId, catId)
-	helper.PanicIfError(err)
+	return notFound(err, ErrMatchNotPending)
 }
 
-func GetMatchById(ctx context.Context, tx *sql.Tx, matchId string) (Match, error) {
-	match := &Match{}
-	var issuedByStr, matchCatDetailStr, userCatDetailStr string
-	SQL := "SELECT id, match_user_email, status, issued_by, match_cat_detail, user_cat_detail, message, created_at FROM matches WHERE id = $1"
+func (s *postgresMatchStore) RejectOtherMatch(ctx context.Context, catId string, matchId string) error {
+	SQL := `UPDATE matches m SET status = 'reject' FROM cats c
+			WHERE c.public_id = $2 AND m.id != $1 AND (m.receiver_cat_id = c.id OR m.issuer_cat_id = c.id) AND m.status != 'approved'`
 
-	err := tx.QueryRowContext(ctx, SQL, matchId).Scan(&match.Id, &match.MatchUserEmail, &match.Status, &issuedByStr, &matchCatDetailStr, &userCatDetailStr, &match.Message, &match.CreatedAt)
+	_, err := s.tx.ExecContext(ctx, SQL, matchId, catId)
 
-	if err == nil {
-		// text to json/struct
-		err := json.Unmarshal([]byte(issuedByStr), &match.IssuedBy)
-		if err != nil {
-			log.Println("Error unmarshalling IssuedBy JSON:", err)
-		}
+	return err
+}
 
-		err = json.Unmarshal([]byte(matchCatDetailStr), &match.MatchCatDetail)
-		if err != nil {
-			log.Println("Error unmarshalling MatchCatDetail JSON:", err)
-		}
+func (s *postgresMatchStore) GetMatchById(ctx context.Context, matchId string) (Match, error) {
+	SQL := matchSelect + " WHERE m.id = $1"
 
-		err = json.Unmarshal([]byte(userCatDetailStr), &match.UserCatDetail)
-		if err != nil {
-			log.Println("Error unmarshalling UserCatDetail JSON:", err)
-		}
-	}
+	match, err := scanMatch(s.tx.QueryRowContext(ctx, SQL, matchId))
 
-	return *match, err
+	return match, notFound(err, ErrMatchNotFound)
 }
 
-func CountCatInMatch(ctx context.Context, tx *sql.Tx, catId string) int {
+func (s *postgresMatchStore) CountCatInMatch(ctx context.Context, catId string) (int, error) {
 	var count int
-	SQL := "SELECT COUNT (*) FROM matches WHERE match_cat_detail->>'id' = $1 OR user_cat_detail->>'id' = $2"
+	SQL := `SELECT COUNT(*) FROM matches m
+			JOIN cats c ON m.receiver_cat_id = c.id OR m.issuer_cat_id = c.id
+			WHERE c.public_id = $1`
 
-	tx.QueryRowContext(ctx, SQL, catId, catId).Scan(&count)
+	err := s.tx.QueryRowContext(ctx, SQL, catId).Scan(&count)
 
-	return count
+	return count, err
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type memoryAuditStore struct {
+	state *memoryState
+}
+
+func (s *memoryAuditStore) Record(ctx context.Context, entry AuditEntry) error {
+	entry.Id = int64(len(s.state.audit) + 1)
+	entry.CreatedAt = time.Now()
+	s.state.audit = append(s.state.audit, entry)
+
+	return nil
+}
+
+func (s *memoryAuditStore) GetAllAudit(ctx context.Context, auditParam AuditParam) ([]AuditEntry, error) {
+	f, err := auditParam.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []AuditEntry{}
+	// newest first
+	for i := len(s.state.audit) - 1; i >= 0; i-- {
+		entry := s.state.audit[i]
+		if f.actorId != "" && entry.ActorId != f.actorId ||
+			f.actorEmail != "" && entry.ActorEmail != f.actorEmail ||
+			f.action != "" && entry.Action != f.action ||
+			f.targetId != "" && entry.TargetId != f.targetId {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if f.offset >= len(entries) {
+		return []AuditEntry{}, nil
+	}
+
+	return entries[f.offset:min(f.offset+f.limit, len(entries))], nil
+}
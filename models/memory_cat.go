@@ -0,0 +1,241 @@
+package models
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+type memoryCatStore struct {
+	state *memoryState
+}
+
+func (s *memoryCatStore) SaveCat(ctx context.Context, cat CatInsertRequest) (string, time.Time, error) {
+	createdAt := time.Now()
+	id := newULID(createdAt)
+
+	s.state.lastCatId++
+	s.state.legacyCatIds[s.state.lastCatId] = id
+
+	s.state.cats[id] = Cat{
+		Id:          id,
+		UserId:      cat.UserId,
+		Name:        cat.Name,
+		Race:        cat.Race,
+		Sex:         cat.Sex,
+		AgeInMonth:  cat.AgeInMonth,
+		ImageUrls:   append([]string{}, cat.ImageUrls...),
+		Description: cat.Description,
+		Private:     cat.Private,
+		CreatedAt:   createdAt,
+	}
+
+	return id, createdAt, nil
+}
+
+// filtered returns the cats matching the filters of f, cursor and paging aside.
+func (s *memoryCatStore) filtered(f catFilter) []Cat {
+	cats := []Cat{}
+	for id, cat := range s.state.cats {
+		if f.owned != nil && (cat.UserId == f.userId) != *f.owned {
+			continue
+		}
+		if cat.Private && (f.viewerId == "" || cat.UserId != f.viewerId) {
+			continue
+		}
+		if f.id != "" && id != f.id {
+			continue
+		}
+		if f.race != "" && cat.Race != f.race {
+			continue
+		}
+		if f.sex != "" && cat.Sex != f.sex {
+			continue
+		}
+		if f.hasMatched != nil && cat.HasMatched != *f.hasMatched {
+			continue
+		}
+		if f.ageOp == ">" && !(cat.AgeInMonth > f.age) ||
+			f.ageOp == "<" && !(cat.AgeInMonth < f.age) ||
+			f.ageOp == "=" && cat.AgeInMonth != f.age {
+			continue
+		}
+		if f.search != "" && !strings.Contains(strings.ToLower(cat.Name), f.search) {
+			continue
+		}
+
+		if f.relevance {
+			cat.relevance = similarity(cat.Name, f.search)
+		}
+		cats = append(cats, cat)
+	}
+
+	return cats
+}
+
+func (s *memoryCatStore) GetAllCat(ctx context.Context, catParam CatParam) (CatPage, error) {
+	f, err := catParam.filter()
+	if err != nil {
+		return CatPage{}, err
+	}
+
+	cats := s.filtered(f)
+	if f.cursor != nil {
+		cats = slices.DeleteFunc(cats, func(cat Cat) bool {
+			return !f.keeps(cat)
+		})
+	}
+
+	slices.SortFunc(cats, f.compare)
+
+	// read in the order of the cursor, see catFilter.page
+	if f.cursor != nil && f.cursor.Before {
+		slices.Reverse(cats)
+	}
+
+	cats = cats[min(f.offset, len(cats)):]
+	if f.limit+1 < len(cats) {
+		cats = cats[:f.limit+1]
+	}
+
+	return f.page(cats), nil
+}
+
+func (s *memoryCatStore) CountCat(ctx context.Context, catParam CatParam, estimate bool) (int, error) {
+	f, err := catParam.filter()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(s.filtered(f)), nil
+}
+
+func (s *memoryCatStore) GetCatById(ctx context.Context, id string) (Cat, error) {
+	cat, ok := s.state.cats[id]
+	if !ok {
+		return Cat{}, ErrCatNotFound
+	}
+
+	return cat, nil
+}
+
+func (s *memoryCatStore) GetCatByLegacyId(ctx context.Context, id int) (Cat, error) {
+	return s.GetCatById(ctx, s.state.legacyCatIds[id])
+}
+
+func (s *memoryCatStore) DestroyCat(ctx context.Context, id string, userId string) error {
+	// a cat without an owner is no one's, as its NULL user_id in SQL
+	cat, ok := s.state.cats[id]
+	if !ok || cat.UserId == "" || cat.UserId != userId {
+		return ErrCatNotFound
+	}
+
+	return s.DestroyCatById(ctx, id)
+}
+
+func (s *memoryCatStore) DestroyCatById(ctx context.Context, id string) error {
+	if _, ok := s.state.cats[id]; !ok {
+		return ErrCatNotFound
+	}
+
+	delete(s.state.cats, id)
+	for legacyId, catId := range s.state.legacyCatIds {
+		if catId == id {
+			delete(s.state.legacyCatIds, legacyId)
+		}
+	}
+
+	// matches reference cats with ON DELETE CASCADE
+	for matchId, match := range s.state.matches {
+		if match.IssuerCatId == id || match.ReceiverCatId == id {
+			delete(s.state.matches, matchId)
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryCatStore) DestroyUserCats(ctx context.Context, userId string) (int, error) {
+	count := 0
+	for id, cat := range s.state.cats {
+		if cat.UserId != userId || s.approved(id) {
+			continue
+		}
+
+		err := s.DestroyCat(ctx, id, userId)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// approved reports whether the cat is in an approved match.
+func (s *memoryCatStore) approved(id string) bool {
+	for _, match := range s.state.matches {
+		if match.Status == "approved" && (match.IssuerCatId == id || match.ReceiverCatId == id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *memoryCatStore) DetachUserCats(ctx context.Context, userId string) (int, error) {
+	count := 0
+	for id, cat := range s.state.cats {
+		if cat.UserId != userId {
+			continue
+		}
+
+		cat.UserId, cat.Private = "", true
+		s.state.cats[id] = cat
+		count++
+	}
+
+	return count, nil
+}
+
+func (s *memoryCatStore) UpdateCatWithSex(ctx context.Context, id string, cat CatInsertRequest) error {
+	old, ok := s.state.cats[id]
+	if !ok || old.UserId != cat.UserId {
+		return ErrCatNotFound
+	}
+
+	old.Sex = cat.Sex
+	s.state.cats[id] = old
+
+	return s.UpdateCatWithoutSex(ctx, id, cat)
+}
+
+func (s *memoryCatStore) UpdateCatWithoutSex(ctx context.Context, id string, cat CatInsertRequest) error {
+	old, ok := s.state.cats[id]
+	if !ok || old.UserId != cat.UserId {
+		return ErrCatNotFound
+	}
+
+	old.Name = cat.Name
+	old.Race = cat.Race
+	old.AgeInMonth = cat.AgeInMonth
+	old.ImageUrls = append([]string{}, cat.ImageUrls...)
+	old.Description = cat.Description
+	old.Private = cat.Private
+	s.state.cats[id] = old
+
+	return nil
+}
+
+// update property hasMatched
+func (s *memoryCatStore) UpdateStatusCat(ctx context.Context, idCat1 string, idCat2 string) error {
+	for _, id := range []string{idCat1, idCat2} {
+		if cat, ok := s.state.cats[id]; ok {
+			cat.HasMatched = true
+			s.state.cats[id] = cat
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type memoryLoginAttemptStore struct {
+	state *memoryState
+}
+
+func (s *memoryLoginAttemptStore) GetLoginAttempt(ctx context.Context, scope string, key string) (LoginAttempt, error) {
+	attempt, ok := s.state.loginAttempts[scope+"/"+key]
+	if !ok {
+		return LoginAttempt{Scope: scope, Key: key}, nil
+	}
+
+	return attempt, nil
+}
+
+func (s *memoryLoginAttemptStore) AddLoginFailure(ctx context.Context, scope string, key string, now time.Time, since time.Time, lockout int) (LoginAttempt, error) {
+	attempt, ok := s.state.loginAttempts[scope+"/"+key]
+	if !ok {
+		attempt = LoginAttempt{Scope: scope, Key: key, LockedUntil: now}
+	}
+
+	if attempt.LastFailureAt.Before(since) || lockout > 0 && attempt.Failures >= lockout {
+		attempt.Failures = 0
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.state.loginAttempts[scope+"/"+key] = attempt
+
+	return attempt, nil
+}
+
+func (s *memoryLoginAttemptStore) LockLoginAttempt(ctx context.Context, scope string, key string, until time.Time) error {
+	attempt, ok := s.state.loginAttempts[scope+"/"+key]
+	if ok {
+		attempt.LockedUntil = until
+		s.state.loginAttempts[scope+"/"+key] = attempt
+	}
+
+	return nil
+}
+
+func (s *memoryLoginAttemptStore) ResetLoginAttempt(ctx context.Context, scope string, key string) error {
+	delete(s.state.loginAttempts, scope+"/"+key)
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type memoryMatchStore struct {
+	state *memoryState
+}
+
+func catDetail(cat Cat) CatDetail {
+	return CatDetail{
+		Id:          cat.Id,
+		Name:        cat.Name,
+		Race:        cat.Race,
+		Sex:         cat.Sex,
+		Description: cat.Description,
+		AgeInMonth:  cat.AgeInMonth,
+		ImageUrls:   cat.ImageUrls,
+		HasMatched:  cat.HasMatched,
+		CreatedAt:   cat.CreatedAt,
+	}
+}
+
+// assemble joins a stored match with the live issuer and cat rows.
+func (s *memoryMatchStore) assemble(m memoryMatch) Match {
+	issuer := s.state.users[m.IssuerId]
+
+	return Match{
+		Id: m.Id,
+		IssuedBy: Issuer{
+			Name:      issuer.Name,
+			Email:     issuer.Email,
+			CreatedAt: m.CreatedAt,
+		},
+		MatchCatDetail: catDetail(s.state.cats[m.ReceiverCatId]),
+		MatchUserId:    m.MatchUserId,
+		Status:         m.Status,
+		UserCatDetail:  catDetail(s.state.cats[m.IssuerCatId]),
+		Message:        m.Message,
+		CreatedAt:      m.CreatedAt,
+	}
+}
+
+func (s *memoryMatchStore) NewMatch(ctx context.Context, match MatchInsertRequest) (string, time.Time, error) {
+	receiverCat, ok := s.state.cats[match.MatchCatId]
+	if !ok {
+		return "", time.Time{}, ErrCatNotFound
+	}
+
+	m := memoryMatch{
+		Id:            newUUID(),
+		IssuerId:      match.IssuerId,
+		IssuerCatId:   match.UserCatId,
+		ReceiverCatId: match.MatchCatId,
+		MatchUserId:   receiverCat.UserId,
+		Message:       match.Message,
+		Status:        "pending",
+		CreatedAt:     time.Now(),
+	}
+	s.state.matches[m.Id] = m
+
+	return m.Id, m.CreatedAt, nil
+}
+
+func (s *memoryMatchStore) CrossCheckMatchCatId(ctx context.Context, matchCatId string, userCatId string) (int, error) {
+	count := 0
+	for _, match := range s.state.matches {
+		if match.ReceiverCatId == matchCatId && match.IssuerCatId == userCatId {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *memoryMatchStore) GetAllMatch(ctx context.Context, userId string) ([]Match, error) {
+	matches := []Match{}
+	for _, match := range s.state.matches {
+		if match.IssuerId == userId || match.MatchUserId == userId && match.Status == "pending" {
+			matches = append(matches, s.assemble(match))
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].CreatedAt.After(matches[j].CreatedAt)
+	})
+
+	return matches, nil
+}
+
+func (s *memoryMatchStore) GetMatchById(ctx context.Context, matchId string) (Match, error) {
+	match, ok := s.state.matches[matchId]
+	if !ok {
+		return Match{}, ErrMatchNotFound
+	}
+
+	return s.assemble(match), nil
+}
+
+func (s *memoryMatchStore) DeleteMatch(ctx context.Context, id string) (string, string, error) {
+	match, ok := s.state.matches[id]
+	if !ok {
+		return "", "", ErrMatchNotFound
+	}
+
+	delete(s.state.matches, id)
+	return match.Status, match.IssuerId, nil
+}
+
+func (s *memoryMatchStore) CancelUserMatches(ctx context.Context, userId string) (int, error) {
+	count := 0
+	for id, match := range s.state.matches {
+		if match.Status == "pending" && (match.IssuerId == userId || match.MatchUserId == userId) {
+			delete(s.state.matches, id)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *memoryMatchStore) DetachUserMatches(ctx context.Context, userId string) error {
+	for id, match := range s.state.matches {
+		if match.IssuerId == userId {
+			match.IssuerId = ""
+		}
+		if match.MatchUserId == userId {
+			match.MatchUserId = ""
+		}
+		s.state.matches[id] = match
+	}
+
+	return nil
+}
+
+func (s *memoryMatchStore) ApproveMatch(ctx context.Context, matchId string) error {
+	match, ok := s.state.matches[matchId]
+	if !ok || match.Status != "pending" {
+		return ErrMatchNotPending
+	}
+
+	match.Status = "approved"
+	s.state.matches[matchId] = match
+
+	return nil
+}
+
+func (s *memoryMatchStore) RejectMatch(ctx context.Context, matchId string) error {
+	match, ok := s.state.matches[matchId]
+	if !ok || match.Status != "pending" {
+		return ErrMatchNotPending
+	}
+
+	match.Status = "reject"
+	s.state.matches[matchId] = match
+
+	return nil
+}
+
+func (s *memoryMatchStore) RejectOtherMatch(ctx context.Context, catId string, matchId string) error {
+	for id, match := range s.state.matches {
+		if id == matchId || match.Status == "approved" {
+			continue
+		}
+
+		if match.ReceiverCatId == catId || match.IssuerCatId == catId {
+			match.Status = "reject"
+			s.state.matches[id] = match
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryMatchStore) CountCatInMatch(ctx context.Context, catId string) (int, error) {
+	count := 0
+	for _, match := range s.state.matches {
+		if match.ReceiverCatId == catId || match.IssuerCatId == catId {
+			count++
+		}
+	}
+
+	return count, nil
+}
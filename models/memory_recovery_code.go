@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type memoryRecoveryCode struct {
+	UserId   string
+	CodeHash string
+	UsedAt   *time.Time
+}
+
+type memoryRecoveryCodeStore struct {
+	state *memoryState
+}
+
+func (s *memoryRecoveryCodeStore) ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	codes := []memoryRecoveryCode{}
+	for _, code := range s.state.recoveryCodes {
+		if code.UserId != userId {
+			codes = append(codes, code)
+		}
+	}
+
+	for _, codeHash := range codeHashes {
+		codes = append(codes, memoryRecoveryCode{UserId: userId, CodeHash: codeHash})
+	}
+	s.state.recoveryCodes = codes
+
+	return nil
+}
+
+func (s *memoryRecoveryCodeStore) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	for i, code := range s.state.recoveryCodes {
+		if code.UserId == userId && code.CodeHash == codeHash && code.UsedAt == nil {
+			now := time.Now()
+			s.state.recoveryCodes[i].UsedAt = &now
+			return nil
+		}
+	}
+
+	return ErrTokenNotFound
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type memorySessionStore struct {
+	state *memoryState
+}
+
+func (s *memorySessionStore) NewSession(ctx context.Context, session Session) (Session, error) {
+	session.Id = newUUID()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	s.state.sessions[session.Id] = session
+
+	return session, nil
+}
+
+func (s *memorySessionStore) GetSession(ctx context.Context, id string) (Session, error) {
+	session, ok := s.state.sessions[id]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+func (s *memorySessionStore) GetUserSessions(ctx context.Context, userId string) ([]Session, error) {
+	sessions := []Session{}
+	for _, session := range s.state.sessions {
+		if session.UserId == userId && session.RevokedAt == nil {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+func (s *memorySessionStore) TouchSession(ctx context.Context, id string, ip string, userAgent string) error {
+	session, ok := s.state.sessions[id]
+	if !ok {
+		return nil
+	}
+
+	session.LastSeenAt = time.Now()
+	session.IP = ip
+	session.UserAgent = userAgent
+	s.state.sessions[id] = session
+
+	return nil
+}
+
+func (s *memorySessionStore) RevokeSession(ctx context.Context, id string) error {
+	session, ok := s.state.sessions[id]
+	if ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+		s.state.sessions[id] = session
+	}
+
+	return nil
+}
+
+func (s *memorySessionStore) RevokeUserSessions(ctx context.Context, userId string, exceptId string) error {
+	now := time.Now()
+	for id, session := range s.state.sessions {
+		if session.UserId == userId && session.RevokedAt == nil && id != exceptId {
+			session.RevokedAt = &now
+			s.state.sessions[id] = session
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type memoryState struct {
+	users   map[string]User
+	cats    map[string]Cat
+	matches map[string]memoryMatch
+
+	// legacyCatIds maps the sequential ids cats had before their ULIDs
+	legacyCatIds map[int]string
+	lastCatId    int
+
+	refreshTokens map[string]RefreshToken
+	sessions      map[string]Session
+	userTokens    map[string]UserToken
+	recoveryCodes []memoryRecoveryCode
+	loginAttempts map[string]LoginAttempt
+	audit         []AuditEntry
+}
+
+type memoryMatch struct {
+	Id            string
+	IssuerId      string
+	IssuerCatId   string
+	ReceiverCatId string
+	MatchUserId   string
+	Message       string
+	Status        string
+	CreatedAt     time.Time
+}
+
+func (m *memoryState) clone() *memoryState {
+	c := &memoryState{
+		users:   make(map[string]User, len(m.users)),
+		cats:    make(map[string]Cat, len(m.cats)),
+		matches: make(map[string]memoryMatch, len(m.matches)),
+
+		legacyCatIds: make(map[int]string, len(m.legacyCatIds)),
+		lastCatId:    m.lastCatId,
+
+		refreshTokens: make(map[string]RefreshToken, len(m.refreshTokens)),
+		sessions:      make(map[string]Session, len(m.sessions)),
+		userTokens:    make(map[string]UserToken, len(m.userTokens)),
+		recoveryCodes: append([]memoryRecoveryCode{}, m.recoveryCodes...),
+		loginAttempts: make(map[string]LoginAttempt, len(m.loginAttempts)),
+		audit:         append([]AuditEntry{}, m.audit...),
+	}
+
+	for k, v := range m.users {
+		c.users[k] = v
+	}
+	for k, v := range m.cats {
+		c.cats[k] = v
+	}
+	for k, v := range m.matches {
+		c.matches[k] = v
+	}
+	for k, v := range m.legacyCatIds {
+		c.legacyCatIds[k] = v
+	}
+	for k, v := range m.refreshTokens {
+		c.refreshTokens[k] = v
+	}
+	for k, v := range m.sessions {
+		c.sessions[k] = v
+	}
+	for k, v := range m.userTokens {
+		c.userTokens[k] = v
+	}
+	for k, v := range m.loginAttempts {
+		c.loginAttempts[k] = v
+	}
+
+	return c
+}
+
+// memoryStore keeps every table in process memory. Transactions are
+// serialized and work on a copy of the state which replaces the shared
+// state on commit, so a rollback simply drops the copy.
+type memoryStore struct {
+	lock  chan struct{}
+	state *memoryState
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		lock: make(chan struct{}, 1),
+		state: &memoryState{
+			users:   map[string]User{},
+			cats:    map[string]Cat{},
+			matches: map[string]memoryMatch{},
+
+			legacyCatIds: map[int]string{},
+
+			refreshTokens: map[string]RefreshToken{},
+			sessions:      map[string]Session{},
+			userTokens:    map[string]UserToken{},
+			loginAttempts: map[string]LoginAttempt{},
+		},
+	}
+}
+
+func (s *memoryStore) Begin(ctx context.Context) (Tx, error) {
+	select {
+	case s.lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return &memoryTx{ctx: ctx, store: s, state: s.state.clone()}, nil
+}
+
+type memoryTx struct {
+	ctx   context.Context
+	store *memoryStore
+	state *memoryState
+	done  bool
+}
+
+func (t *memoryTx) Cats() CatStore {
+	return &memoryCatStore{state: t.state}
+}
+
+func (t *memoryTx) Matches() MatchStore {
+	return &memoryMatchStore{state: t.state}
+}
+
+func (t *memoryTx) Users() UserStore {
+	return &memoryUserStore{state: t.state}
+}
+
+func (t *memoryTx) Tokens() TokenStore {
+	return &memoryTokenStore{state: t.state}
+}
+
+func (t *memoryTx) Sessions() SessionStore {
+	return &memorySessionStore{state: t.state}
+}
+
+func (t *memoryTx) UserTokens() UserTokenStore {
+	return &memoryUserTokenStore{state: t.state}
+}
+
+func (t *memoryTx) RecoveryCodes() RecoveryCodeStore {
+	return &memoryRecoveryCodeStore{state: t.state}
+}
+
+func (t *memoryTx) LoginAttempts() LoginAttemptStore {
+	return &memoryLoginAttemptStore{state: t.state}
+}
+
+func (t *memoryTx) Audit() AuditStore {
+	return &memoryAuditStore{state: t.state}
+}
+
+func (t *memoryTx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	// like database/sql, a transaction whose context is done is rolled back
+	if err := t.ctx.Err(); err != nil {
+		t.Rollback()
+		return err
+	}
+
+	t.done = true
+	t.store.state = t.state
+	<-t.store.lock
+
+	return nil
+}
+
+func (t *memoryTx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+	<-t.store.lock
+
+	return nil
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type memoryTokenStore struct {
+	state *memoryState
+}
+
+func (s *memoryTokenStore) NewRefreshToken(ctx context.Context, token RefreshToken) (RefreshToken, error) {
+	token.Id = newUUID()
+	token.CreatedAt = time.Now()
+	s.state.refreshTokens[token.Id] = token
+
+	return token, nil
+}
+
+func (s *memoryTokenStore) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	for _, token := range s.state.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return RefreshToken{}, ErrTokenNotFound
+}
+
+func (s *memoryTokenStore) UseRefreshToken(ctx context.Context, id string) error {
+	token, ok := s.state.refreshTokens[id]
+	if !ok || token.UsedAt != nil {
+		return ErrTokenReused
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	s.state.refreshTokens[id] = token
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+type memoryUserStore struct {
+	state *memoryState
+}
+
+func (s *memoryUserStore) SaveUser(ctx context.Context, user User) (User, error) {
+	if _, err := s.GetUserByEmail(ctx, user.Email); err == nil {
+		return user, ErrEmailTaken
+	}
+
+	user.Id = newUUID()
+	user.Role = RoleUser
+	s.state.users[user.Id] = user
+
+	return user, nil
+}
+
+func (s *memoryUserStore) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	for _, user := range s.state.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return User{}, ErrUserNotFound
+}
+
+func (s *memoryUserStore) GetUserById(ctx context.Context, id string) (User, error) {
+	user, ok := s.state.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (s *memoryUserStore) SearchUsers(ctx context.Context, userParam UserParam) ([]User, error) {
+	f, err := userParam.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	users := []User{}
+	for _, user := range s.state.users {
+		if f.search != "" && !strings.Contains(strings.ToLower(user.Email), f.search) &&
+			!strings.Contains(strings.ToLower(user.Name), f.search) {
+			continue
+		}
+		if f.role != "" && user.Role != f.role {
+			continue
+		}
+		if f.disabled != nil && (user.DisabledAt != nil) != *f.disabled {
+			continue
+		}
+
+		user.Password = ""
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Email < users[j].Email
+	})
+
+	if f.offset >= len(users) {
+		return []User{}, nil
+	}
+
+	return users[f.offset:min(f.offset+f.limit, len(users))], nil
+}
+
+func (s *memoryUserStore) SetUserRole(ctx context.Context, id string, role string) error {
+	user, ok := s.state.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	user.Role = role
+	s.state.users[id] = user
+
+	return nil
+}
+
+func (s *memoryUserStore) SetUserDisabled(ctx context.Context, id string, disabled bool) error {
+	user, ok := s.state.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if !disabled {
+		user.DisabledAt = nil
+	} else if user.DisabledAt == nil {
+		now := time.Now()
+		user.DisabledAt = &now
+	}
+	s.state.users[id] = user
+
+	return nil
+}
+
+func (s *memoryUserStore) SetUserPassword(ctx context.Context, id string, password string) error {
+	user, ok := s.state.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	user.Password = password
+	s.state.users[id] = user
+
+	return nil
+}
+
+func (s *memoryUserStore) SetUserEmail(ctx context.Context, id string, email string) error {
+	user, ok := s.state.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if other, err := s.GetUserByEmail(ctx, email); err == nil && other.Id != id {
+		return ErrEmailTaken
+	}
+
+	now := time.Now()
+	user.Email = email
+	user.EmailVerifiedAt = &now
+	s.state.users[id] = user
+
+	return nil
+}
+
+func (s *memoryUserStore) SetEmailVerified(ctx context.Context, id string) error {
+	user, ok := s.state.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		s.state.users[id] = user
+	}
+
+	return nil
+}
+
+func (s *memoryUserStore) SetTOTPSecret(ctx context.Context, id string, secret string) error {
+	user, ok := s.state.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	s.state.users[id] = user
+
+	return nil
+}
+
+func (s *memoryUserStore) EnableTOTP(ctx context.Context, id string) error {
+	user, ok := s.state.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if user.TOTPEnabledAt == nil {
+		now := time.Now()
+		user.TOTPEnabledAt = &now
+		s.state.users[id] = user
+	}
+
+	return nil
+}
+
+func (s *memoryUserStore) UseTOTPStep(ctx context.Context, id string, step int64) error {
+	user, ok := s.state.users[id]
+	if !ok || user.TOTPLastStep >= step {
+		return ErrTokenReused
+	}
+
+	user.TOTPLastStep = step
+	s.state.users[id] = user
+
+	return nil
+}
+
+func (s *memoryUserStore) SetUserProfile(ctx context.Context, user User) error {
+	old, ok := s.state.users[user.Id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	old.Name = user.Name
+	old.Bio = user.Bio
+	old.AvatarUrl = user.AvatarUrl
+	old.City = user.City
+	s.state.users[user.Id] = old
+
+	return nil
+}
+
+func (s *memoryUserStore) DeleteUser(ctx context.Context, id string) error {
+	if _, ok := s.state.users[id]; !ok {
+		return ErrUserNotFound
+	}
+
+	delete(s.state.users, id)
+
+	// tokens, sessions and recovery codes reference users with ON DELETE CASCADE
+	for id, token := range s.state.refreshTokens {
+		if token.UserId == id {
+			delete(s.state.refreshTokens, id)
+		}
+	}
+	for id, session := range s.state.sessions {
+		if session.UserId == id {
+			delete(s.state.sessions, id)
+		}
+	}
+	for id, token := range s.state.userTokens {
+		if token.UserId == id {
+			delete(s.state.userTokens, id)
+		}
+	}
+
+	codes := s.state.recoveryCodes[:0]
+	for _, code := range s.state.recoveryCodes {
+		if code.UserId != id {
+			codes = append(codes, code)
+		}
+	}
+	s.state.recoveryCodes = codes
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type memoryUserTokenStore struct {
+	state *memoryState
+}
+
+func (s *memoryUserTokenStore) NewUserToken(ctx context.Context, token UserToken) (UserToken, error) {
+	token.Id = newUUID()
+	token.CreatedAt = time.Now()
+	s.state.userTokens[token.Id] = token
+
+	return token, nil
+}
+
+func (s *memoryUserTokenStore) GetUserTokenByHash(ctx context.Context, purpose string, tokenHash string) (UserToken, error) {
+	for _, token := range s.state.userTokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return UserToken{}, ErrTokenNotFound
+}
+
+func (s *memoryUserTokenStore) UseUserToken(ctx context.Context, id string) error {
+	token, ok := s.state.userTokens[id]
+	if !ok || token.UsedAt != nil {
+		return ErrTokenReused
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	s.state.userTokens[id] = token
+
+	return nil
+}
+
+func (s *memoryUserTokenStore) DeleteUserTokens(ctx context.Context, userId string, purpose string) error {
+	for id, token := range s.state.userTokens {
+		if token.UserId == userId && token.Purpose == purpose && token.UsedAt == nil {
+			delete(s.state.userTokens, id)
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryUserTokenStore) GetLatestUserToken(ctx context.Context, userId string, purpose string) (UserToken, error) {
+	latest := UserToken{}
+	for _, token := range s.state.userTokens {
+		if token.UserId == userId && token.Purpose == purpose && token.CreatedAt.After(latest.CreatedAt) {
+			latest = token
+		}
+	}
+
+	if latest.Id == "" {
+		return latest, ErrTokenNotFound
+	}
+
+	return latest, nil
+}
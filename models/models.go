@@ -1,17 +1,16 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"strconv"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
-var db *sql.DB
-
 func InitDb(url string) (*sql.DB, error) {
-	var err error
-
-	db, err = sql.Open("postgres", url)
+	db, err := sql.Open("postgres", url)
 	if err != nil {
 		return db, err
 	}
@@ -19,11 +18,89 @@ func InitDb(url string) (*sql.DB, error) {
 	return db, db.Ping()
 }
 
-func StartTx() *sql.Tx {
-	tx, err := db.Begin()
+type postgresStore struct {
+	db               *sql.DB
+	statementTimeout time.Duration
+}
+
+// NewPostgresStore returns a Store backed by db. Every statement of a
+// transaction is limited to statementTimeout, or to the time left before
+// the context deadline when that is shorter. Zero disables the limit.
+func NewPostgresStore(db *sql.DB, statementTimeout time.Duration) Store {
+	return &postgresStore{db: db, statementTimeout: statementTimeout}
+}
+
+func (s *postgresStore) Begin(ctx context.Context) (Tx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	timeout := s.statementTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	if timeout > 0 {
+		// statement_timeout = 0 means no limit in postgres
+		ms := max(timeout.Milliseconds(), 1)
+
+		_, err = tx.ExecContext(ctx, "SELECT set_config('statement_timeout', $1, true)", strconv.FormatInt(ms, 10))
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 	}
 
-	return tx
+	return &postgresTx{tx: tx}, nil
+}
+
+type postgresTx struct {
+	tx *sql.Tx
+}
+
+func (t *postgresTx) Cats() CatStore {
+	return &postgresCatStore{tx: t.tx}
+}
+
+func (t *postgresTx) Matches() MatchStore {
+	return &postgresMatchStore{tx: t.tx}
+}
+
+func (t *postgresTx) Users() UserStore {
+	return &postgresUserStore{tx: t.tx}
+}
+
+func (t *postgresTx) Tokens() TokenStore {
+	return &postgresTokenStore{tx: t.tx}
+}
+
+func (t *postgresTx) Sessions() SessionStore {
+	return &postgresSessionStore{tx: t.tx}
+}
+
+func (t *postgresTx) UserTokens() UserTokenStore {
+	return &postgresUserTokenStore{tx: t.tx}
+}
+
+func (t *postgresTx) RecoveryCodes() RecoveryCodeStore {
+	return &postgresRecoveryCodeStore{tx: t.tx}
+}
+
+func (t *postgresTx) LoginAttempts() LoginAttemptStore {
+	return &postgresLoginAttemptStore{tx: t.tx}
+}
+
+func (t *postgresTx) Audit() AuditStore {
+	return &postgresAuditStore{tx: t.tx}
+}
+
+func (t *postgresTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *postgresTx) Rollback() error {
+	return t.tx.Rollback()
 }
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+type postgresRecoveryCodeStore struct {
+	tx *sql.Tx
+}
+
+func (s *postgresRecoveryCodeStore) ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	_, err := s.tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId)
+	if err != nil {
+		return err
+	}
+
+	SQL := "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)"
+	for _, codeHash := range codeHashes {
+		_, err = s.tx.ExecContext(ctx, SQL, userId, codeHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *postgresRecoveryCodeStore) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	SQL := `UPDATE recovery_codes SET used_at = NOW()
+			WHERE id = (SELECT id FROM recovery_codes WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL LIMIT 1)
+			RETURNING id`
+
+	var id string
+	err := s.tx.QueryRowContext(ctx, SQL, userId, codeHash).Scan(&id)
+
+	return notFound(err, ErrTokenNotFound)
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"time"
+)
+
+// uuidPattern matches the text form of a UUID. Postgres rejects comparing a
+// UUID column with anything else and aborts the transaction, so ids from
+// requests and tokens are checked before they are queried.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Session is a login of a user on a device. Its id is the id of the refresh
+// token family rotated by the device and the sid claim of its access tokens.
+type Session struct {
+	Id         string     `json:"id"`
+	UserId     string     `json:"-"`
+	UserAgent  string     `json:"userAgent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+type postgresSessionStore struct {
+	tx *sql.Tx
+}
+
+func (s *postgresSessionStore) NewSession(ctx context.Context, session Session) (Session, error) {
+	SQL := `INSERT INTO sessions (user_id, user_agent, ip) VALUES ($1, $2, $3)
+			RETURNING id, created_at, last_seen_at`
+
+	err := s.tx.QueryRowContext(ctx, SQL, session.UserId, session.UserAgent, session.IP).
+		Scan(&session.Id, &session.CreatedAt, &session.LastSeenAt)
+
+	return session, err
+}
+
+func (s *postgresSessionStore) GetSession(ctx context.Context, id string) (Session, error) {
+	session := Session{}
+	if !uuidPattern.MatchString(id) {
+		return session, ErrSessionNotFound
+	}
+
+	SQL := `SELECT id, user_id, user_agent, ip, created_at, last_seen_at, revoked_at
+			FROM sessions WHERE id = $1`
+
+	err := s.tx.QueryRowContext(ctx, SQL, id).Scan(&session.Id, &session.UserId, &session.UserAgent,
+		&session.IP, &session.CreatedAt, &session.LastSeenAt, &session.RevokedAt)
+
+	return session, notFound(err, ErrSessionNotFound)
+}
+
+func (s *postgresSessionStore) GetUserSessions(ctx context.Context, userId string) ([]Session, error) {
+	SQL := `SELECT id, user_id, user_agent, ip, created_at, last_seen_at, revoked_at
+			FROM sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC`
+
+	rows, err := s.tx.QueryContext(ctx, SQL, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		session := Session{}
+		err := rows.Scan(&session.Id, &session.UserId, &session.UserAgent, &session.IP,
+			&session.CreatedAt, &session.LastSeenAt, &session.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (s *postgresSessionStore) TouchSession(ctx context.Context, id string, ip string, userAgent string) error {
+	SQL := "UPDATE sessions SET last_seen_at = NOW(), ip = $2, user_agent = $3 WHERE id = $1"
+
+	_, err := s.tx.ExecContext(ctx, SQL, id, ip, userAgent)
+
+	return err
+}
+
+func (s *postgresSessionStore) RevokeSession(ctx context.Context, id string) error {
+	SQL := "UPDATE sessions SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1"
+
+	_, err := s.tx.ExecContext(ctx, SQL, id)
+
+	return err
+}
+
+func (s *postgresSessionStore) RevokeUserSessions(ctx context.Context, userId string, exceptId string) error {
+	SQL := `UPDATE sessions SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL AND id::TEXT != $2`
+
+	_, err := s.tx.ExecContext(ctx, SQL, userId, exceptId)
+
+	return err
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type CatStore interface {
+	SaveCat(ctx context.Context, cat CatInsertRequest) (string, time.Time, error)
+	GetAllCat(ctx context.Context, catParam CatParam) (CatPage, error)
+	// CountCat counts the cats of every page of catParam, estimated from the
+	// table statistics when estimate is set.
+	CountCat(ctx context.Context, catParam CatParam, estimate bool) (int, error)
+	GetCatById(ctx context.Context, id string) (Cat, error)
+	// GetCatByLegacyId finds a cat by the sequential id it was exposed with
+	// before it had a ULID.
+	GetCatByLegacyId(ctx context.Context, id int) (Cat, error)
+	DestroyCat(ctx context.Context, id string, userId string) error
+	// DestroyCatById deletes a cat whoever owns it, or of no owner anymore.
+	DestroyCatById(ctx context.Context, id string) error
+	// DestroyUserCats deletes the cats of the user with their matches, but
+	// for the cats in an approved match.
+	DestroyUserCats(ctx context.Context, userId string) (int, error)
+	// DetachUserCats leaves the cats of the user without an owner and
+	// private, for a user about to be deleted.
+	DetachUserCats(ctx context.Context, userId string) (int, error)
+	UpdateCatWithSex(ctx context.Context, id string, cat CatInsertRequest) error
+	UpdateCatWithoutSex(ctx context.Context, id string, cat CatInsertRequest) error
+	UpdateStatusCat(ctx context.Context, idCat1 string, idCat2 string) error
+}
+
+type MatchStore interface {
+	NewMatch(ctx context.Context, match MatchInsertRequest) (string, time.Time, error)
+	CrossCheckMatchCatId(ctx context.Context, matchCatId string, userCatId string) (int, error)
+	GetAllMatch(ctx context.Context, userId string) ([]Match, error)
+	GetMatchById(ctx context.Context, matchId string) (Match, error)
+	// DeleteMatch returns the status and the issuer id of the deleted match.
+	DeleteMatch(ctx context.Context, id string) (string, string, error)
+	ApproveMatch(ctx context.Context, matchId string) error
+	RejectMatch(ctx context.Context, matchId string) error
+	RejectOtherMatch(ctx context.Context, catId string, matchId string) error
+	CountCatInMatch(ctx context.Context, catId string) (int, error)
+	// CancelUserMatches deletes the pending matches the user issued or
+	// received and returns how many.
+	CancelUserMatches(ctx context.Context, userId string) (int, error)
+	// DetachUserMatches clears the user from the matches left, for a user
+	// about to be deleted.
+	DetachUserMatches(ctx context.Context, userId string) error
+}
+
+type UserStore interface {
+	SaveUser(ctx context.Context, user User) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserById(ctx context.Context, id string) (User, error)
+	SearchUsers(ctx context.Context, userParam UserParam) ([]User, error)
+	SetUserRole(ctx context.Context, id string, role string) error
+	SetUserDisabled(ctx context.Context, id string, disabled bool) error
+	SetUserPassword(ctx context.Context, id string, password string) error
+	// SetUserEmail changes the email of the user to an address it verified.
+	SetUserEmail(ctx context.Context, id string, email string) error
+	SetEmailVerified(ctx context.Context, id string) error
+	// SetTOTPSecret starts a new TOTP enrollment, an empty secret turns TOTP off.
+	SetTOTPSecret(ctx context.Context, id string, secret string) error
+	EnableTOTP(ctx context.Context, id string) error
+	// UseTOTPStep accepts a code of time step once, returning ErrTokenReused
+	// for a step not after the last one.
+	UseTOTPStep(ctx context.Context, id string, step int64) error
+	// SetUserProfile saves the name, bio, avatar URL and city of user.
+	SetUserProfile(ctx context.Context, user User) error
+	// DeleteUser deletes the user with its tokens, sessions and codes, its
+	// cats and matches must be gone already.
+	DeleteUser(ctx context.Context, id string) error
+}
+
+type TokenStore interface {
+	// NewRefreshToken saves token in the family of its session.
+	NewRefreshToken(ctx context.Context, token RefreshToken) (RefreshToken, error)
+	// GetRefreshTokenByHash locks the token until the transaction ends.
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
+	UseRefreshToken(ctx context.Context, id string) error
+}
+
+type SessionStore interface {
+	NewSession(ctx context.Context, session Session) (Session, error)
+	GetSession(ctx context.Context, id string) (Session, error)
+	// GetUserSessions returns the sessions of the user not revoked, last seen first.
+	GetUserSessions(ctx context.Context, userId string) ([]Session, error)
+	TouchSession(ctx context.Context, id string, ip string, userAgent string) error
+	RevokeSession(ctx context.Context, id string) error
+	// RevokeUserSessions revokes every session of the user but exceptId.
+	RevokeUserSessions(ctx context.Context, userId string, exceptId string) error
+}
+
+type UserTokenStore interface {
+	NewUserToken(ctx context.Context, token UserToken) (UserToken, error)
+	// GetUserTokenByHash locks the token until the transaction ends.
+	GetUserTokenByHash(ctx context.Context, purpose string, tokenHash string) (UserToken, error)
+	UseUserToken(ctx context.Context, id string) error
+	// DeleteUserTokens deletes the unused tokens of the user for purpose.
+	DeleteUserTokens(ctx context.Context, userId string, purpose string) error
+	GetLatestUserToken(ctx context.Context, userId string, purpose string) (UserToken, error)
+}
+
+type RecoveryCodeStore interface {
+	// ReplaceRecoveryCodes deletes every recovery code of the user and saves codeHashes.
+	ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error
+	// UseRecoveryCode returns ErrTokenNotFound when the user has no such unused code.
+	UseRecoveryCode(ctx context.Context, userId string, codeHash string) error
+}
+
+type LoginAttemptStore interface {
+	// GetLoginAttempt locks the counter until the transaction ends, a key
+	// without failures has a zero counter.
+	GetLoginAttempt(ctx context.Context, scope string, key string) (LoginAttempt, error)
+	// AddLoginFailure counts a failure at now in a single statement, so
+	// concurrent failures all count, and returns the counter. The count
+	// restarts when the last failure is before since or reached lockout.
+	AddLoginFailure(ctx context.Context, scope string, key string, now time.Time, since time.Time, lockout int) (LoginAttempt, error)
+	LockLoginAttempt(ctx context.Context, scope string, key string, until time.Time) error
+	ResetLoginAttempt(ctx context.Context, scope string, key string) error
+}
+
+type AuditStore interface {
+	Record(ctx context.Context, entry AuditEntry) error
+	GetAllAudit(ctx context.Context, auditParam AuditParam) ([]AuditEntry, error)
+}
+
+// Tx is a unit of work, every store returned by it shares the same transaction.
+type Tx interface {
+	Cats() CatStore
+	Matches() MatchStore
+	Users() UserStore
+	Tokens() TokenStore
+	Sessions() SessionStore
+	UserTokens() UserTokenStore
+	RecoveryCodes() RecoveryCodeStore
+	LoginAttempts() LoginAttemptStore
+	Audit() AuditStore
+	Commit() error
+	Rollback() error
+}
+
+type Store interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// WithTx runs fn inside a transaction bound to ctx. The transaction is
+// committed when fn returns nil and rolled back when fn returns an error or
+// panics, or when ctx is done.
+func WithTx(ctx context.Context, store Store, fn func(tx Tx) error) (err error) {
+	tx, err := store.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RefreshToken is one token of a refresh token family. Every login starts a
+// new family and every refresh rotates the token inside its family; the
+// family is the Session an access token is bound to.
+type RefreshToken struct {
+	Id        string
+	FamilyId  string
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type postgresTokenStore struct {
+	tx *sql.Tx
+}
+
+func (s *postgresTokenStore) NewRefreshToken(ctx context.Context, token RefreshToken) (RefreshToken, error) {
+	SQL := `INSERT INTO refresh_tokens (family_id, user_id, token_hash, expires_at)
+			VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+
+	err := s.tx.QueryRowContext(ctx, SQL, token.FamilyId, token.UserId, token.TokenHash, token.ExpiresAt).
+		Scan(&token.Id, &token.CreatedAt)
+
+	return token, err
+}
+
+func (s *postgresTokenStore) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	token := RefreshToken{}
+	// the row stays locked until the transaction ends so a token can only be rotated once
+	SQL := `SELECT id, family_id, user_id, token_hash, expires_at, used_at, created_at
+			FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`
+
+	err := s.tx.QueryRowContext(ctx, SQL, tokenHash).Scan(&token.Id, &token.FamilyId, &token.UserId,
+		&token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+
+	return token, notFound(err, ErrTokenNotFound)
+}
+
+func (s *postgresTokenStore) UseRefreshToken(ctx context.Context, id string) error {
+	SQL := "UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id).Scan(&id)
+
+	return notFound(err, ErrTokenReused)
+}
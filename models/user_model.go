@@ -3,24 +3,101 @@ package models
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
-	"github.com/malikfajr/cats-social/helper"
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
 )
 
 type User struct {
-	Email    string `json:"email" validate:"required,email"`
-	Name     string `json:"name" validate:"required,min=5,max=50"`
-	Password string `json:"password" validate:"required,min=5,max=15"`
+	Id         string     `json:"-"`
+	Email      string     `json:"email" validate:"required,email"`
+	Name       string     `json:"name" validate:"required,min=5,max=50"`
+	Password   string     `json:"password" validate:"required"`
+	Role       string     `json:"-"`
+	DisabledAt *time.Time `json:"-"`
+
+	EmailVerifiedAt *time.Time `json:"-"`
+
+	// TOTPSecret is set from enrollment on, TOTPEnabledAt once confirmed.
+	// TOTPLastStep is the time step of the last accepted code, which cannot
+	// be used again.
+	TOTPSecret    string     `json:"-"`
+	TOTPEnabledAt *time.Time `json:"-"`
+	TOTPLastStep  int64      `json:"-"`
+
+	Bio       string `json:"-"`
+	AvatarUrl string `json:"-"`
+	City      string `json:"-"`
+}
+
+type UserParam struct {
+	Search      string
+	Role        string
+	DisabledStr string
+	Limit       string
+	Offset      string
+}
+
+// userFilter is the parsed form of UserParam shared by every UserStore.
+type userFilter struct {
+	search   string
+	role     string
+	disabled *bool
+	limit    int
+	offset   int
+}
+
+func (userParam UserParam) filter() (userFilter, error) {
+	f := userFilter{
+		search: strings.ToLower(userParam.Search),
+		role:   userParam.Role,
+		limit:  20,
+	}
+
+	if userParam.DisabledStr != "" {
+		disabled, err := strconv.ParseBool(userParam.DisabledStr)
+		if err != nil {
+			return f, fmt.Errorf("%w: disabled %q", ErrInvalidParam, userParam.DisabledStr)
+		}
+		f.disabled = &disabled
+	}
+
+	if userParam.Limit != "" {
+		limit, err := strconv.Atoi(userParam.Limit)
+		if err != nil || limit < 1 || limit > 100 {
+			return f, fmt.Errorf("%w: limit %q", ErrInvalidParam, userParam.Limit)
+		}
+		f.limit = limit
+	}
+
+	if userParam.Offset != "" {
+		offset, err := strconv.Atoi(userParam.Offset)
+		if err != nil || offset < 0 {
+			return f, fmt.Errorf("%w: offset %q", ErrInvalidParam, userParam.Offset)
+		}
+		f.offset = offset
+	}
+
+	return f, nil
+}
+
+type postgresUserStore struct {
+	tx *sql.Tx
 }
 
-func SaveUser(ctx context.Context, tx *sql.Tx, user User) User {
-	SQL := "INSERT INTO users (email, name, password) VALUES ($1, $2, $3);"
+func (s *postgresUserStore) SaveUser(ctx context.Context, user User) (User, error) {
+	SQL := "INSERT INTO users (email, name, password) VALUES ($1, $2, $3) RETURNING id, role;"
 
-	_, err := tx.ExecContext(ctx, SQL, user.Email, user.Name, user.Password)
-	helper.PanicIfError(err)
+	err := s.tx.QueryRowContext(ctx, SQL, user.Email, user.Name, user.Password).Scan(&user.Id, &user.Role)
 
-	return user
+	return user, conflict(err, ErrEmailTaken)
 }
 
 type Credential struct {
@@ -28,21 +105,157 @@ type Credential struct {
 	Password string `json:"password" validate:"required,string,min=5,max=15"`
 }
 
-func GetUserByEmail(ctx context.Context, tx *sql.Tx, email string) (User, error) {
+// userSelect reads every column of users in the order of scanUser.
+const userSelect = `SELECT id, email, password, name, role, disabled_at, email_verified_at,
+		totp_secret, totp_enabled_at, totp_last_step, bio, avatar_url, city
+	FROM users`
+
+func scanUser(row rowScanner) (User, error) {
 	user := User{}
 
-	SQL := "SELECT email, password, name FROM users WHERE email = $1;"
+	err := row.Scan(&user.Id, &user.Email, &user.Password, &user.Name, &user.Role, &user.DisabledAt, &user.EmailVerifiedAt,
+		&user.TOTPSecret, &user.TOTPEnabledAt, &user.TOTPLastStep, &user.Bio, &user.AvatarUrl, &user.City)
+
+	return user, err
+}
+
+func (s *postgresUserStore) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	user, err := scanUser(s.tx.QueryRowContext(ctx, userSelect+" WHERE email = $1", email))
+
+	return user, notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) GetUserById(ctx context.Context, id string) (User, error) {
+	user, err := scanUser(s.tx.QueryRowContext(ctx, userSelect+" WHERE id = $1", id))
+
+	return user, notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) SearchUsers(ctx context.Context, userParam UserParam) ([]User, error) {
+	SQL := "SELECT id, email, name, role, disabled_at FROM users WHERE TRUE"
+
+	params := make([]interface{}, 0)
+	f, err := userParam.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	if search := f.search; search != "" {
+		SQL += fmt.Sprintf(" AND (LOWER(email) LIKE $%d OR LOWER(name) LIKE $%d)", len(params)+1, len(params)+1)
+		params = append(params, "%"+search+"%")
+	}
+
+	if role := f.role; role != "" {
+		SQL += fmt.Sprintf(" AND CAST(role AS TEXT) = $%d", len(params)+1)
+		params = append(params, role)
+	}
+
+	if f.disabled != nil {
+		if *f.disabled {
+			SQL += " AND disabled_at IS NOT NULL"
+		} else {
+			SQL += " AND disabled_at IS NULL"
+		}
+	}
+
+	SQL += fmt.Sprintf(" ORDER BY email LIMIT %d OFFSET %d", f.limit, f.offset)
 
-	row, err := tx.QueryContext(ctx, SQL, email)
+	rows, err := s.tx.QueryContext(ctx, SQL, params...)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer row.Close()
+	defer rows.Close()
 
-	if row.Next() == false {
-		return user, errors.New("user not found")
+	users := []User{}
+	for rows.Next() {
+		user := User{}
+		err := rows.Scan(&user.Id, &user.Email, &user.Name, &user.Role, &user.DisabledAt)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
 	}
 
-	row.Scan(&user.Email, &user.Password, &user.Name)
-	return user, nil
+	return users, rows.Err()
+}
+
+func (s *postgresUserStore) SetUserRole(ctx context.Context, id string, role string) error {
+	SQL := "UPDATE users SET role = $2 WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id, role).Scan(&id)
+
+	return notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) SetUserDisabled(ctx context.Context, id string, disabled bool) error {
+	SQL := "UPDATE users SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) END WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id, disabled).Scan(&id)
+
+	return notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) SetUserPassword(ctx context.Context, id string, password string) error {
+	SQL := "UPDATE users SET password = $2 WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id, password).Scan(&id)
+
+	return notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) SetUserEmail(ctx context.Context, id string, email string) error {
+	SQL := "UPDATE users SET email = $2, email_verified_at = NOW() WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id, email).Scan(&id)
+
+	return notFound(conflict(err, ErrEmailTaken), ErrUserNotFound)
+}
+
+func (s *postgresUserStore) SetEmailVerified(ctx context.Context, id string) error {
+	SQL := "UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id).Scan(&id)
+
+	return notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) SetTOTPSecret(ctx context.Context, id string, secret string) error {
+	SQL := "UPDATE users SET totp_secret = $2, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id, secret).Scan(&id)
+
+	return notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) EnableTOTP(ctx context.Context, id string) error {
+	SQL := "UPDATE users SET totp_enabled_at = COALESCE(totp_enabled_at, NOW()) WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id).Scan(&id)
+
+	return notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) UseTOTPStep(ctx context.Context, id string, step int64) error {
+	SQL := "UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id, step).Scan(&id)
+
+	return notFound(err, ErrTokenReused)
+}
+
+func (s *postgresUserStore) SetUserProfile(ctx context.Context, user User) error {
+	SQL := "UPDATE users SET name = $2, bio = $3, avatar_url = $4, city = $5 WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, user.Id, user.Name, user.Bio, user.AvatarUrl, user.City).Scan(&user.Id)
+
+	return notFound(err, ErrUserNotFound)
+}
+
+func (s *postgresUserStore) DeleteUser(ctx context.Context, id string) error {
+	SQL := "DELETE FROM users WHERE id = $1 RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id).Scan(&id)
+
+	return notFound(err, ErrUserNotFound)
 }
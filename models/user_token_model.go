@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+	PurposeMFAChallenge  = "mfa_challenge"
+	PurposeEmailChange   = "email_change"
+)
+
+// UserToken is a single use token mailed to a user, e.g. a password reset
+// link. Only its hash is stored. Email is the new address of an email
+// change.
+type UserToken struct {
+	Id        string
+	UserId    string
+	Email     string
+	Purpose   string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type postgresUserTokenStore struct {
+	tx *sql.Tx
+}
+
+func (s *postgresUserTokenStore) NewUserToken(ctx context.Context, token UserToken) (UserToken, error) {
+	SQL := `INSERT INTO user_tokens (user_id, email, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at`
+
+	err := s.tx.QueryRowContext(ctx, SQL, token.UserId, token.Email, token.Purpose, token.TokenHash, token.ExpiresAt).
+		Scan(&token.Id, &token.CreatedAt)
+
+	return token, err
+}
+
+func (s *postgresUserTokenStore) GetUserTokenByHash(ctx context.Context, purpose string, tokenHash string) (UserToken, error) {
+	token := UserToken{}
+	SQL := `SELECT id, user_id, email, purpose, token_hash, expires_at, used_at, created_at
+			FROM user_tokens WHERE purpose = $1 AND token_hash = $2 FOR UPDATE`
+
+	err := s.tx.QueryRowContext(ctx, SQL, purpose, tokenHash).Scan(&token.Id, &token.UserId, &token.Email, &token.Purpose,
+		&token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+
+	return token, notFound(err, ErrTokenNotFound)
+}
+
+func (s *postgresUserTokenStore) UseUserToken(ctx context.Context, id string) error {
+	SQL := "UPDATE user_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL RETURNING id"
+
+	err := s.tx.QueryRowContext(ctx, SQL, id).Scan(&id)
+
+	return notFound(err, ErrTokenReused)
+}
+
+func (s *postgresUserTokenStore) DeleteUserTokens(ctx context.Context, userId string, purpose string) error {
+	SQL := "DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL"
+
+	_, err := s.tx.ExecContext(ctx, SQL, userId, purpose)
+
+	return err
+}
+
+func (s *postgresUserTokenStore) GetLatestUserToken(ctx context.Context, userId string, purpose string) (UserToken, error) {
+	token := UserToken{}
+	SQL := `SELECT id, user_id, email, purpose, token_hash, expires_at, used_at, created_at
+			FROM user_tokens WHERE user_id = $1 AND purpose = $2 ORDER BY created_at DESC LIMIT 1`
+
+	err := s.tx.QueryRowContext(ctx, SQL, userId, purpose).Scan(&token.Id, &token.UserId, &token.Email, &token.Purpose,
+		&token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+
+	return token, notFound(err, ErrTokenNotFound)
+}
@@ -0,0 +1,65 @@
+// Package password hashes passwords into PHC formatted strings and verifies
+// them with whichever algorithm they were hashed with, so the configured
+// algorithm can change while older hashes keep working.
+package password
+
+import (
+	"errors"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+type Hasher interface {
+	// Hash returns the PHC string of password, salted.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash, a hash of this algorithm.
+	Verify(hash string, password string) (bool, error)
+	// Owns reports whether hash was made by this algorithm.
+	Owns(hash string) bool
+	// Current reports whether hash was made with the parameters of this hasher.
+	Current(hash string) bool
+}
+
+// algorithms verify the hashes of every supported algorithm, whatever their
+// parameters.
+var algorithms = []Hasher{Argon2id{}, Bcrypt{}}
+
+var current Hasher
+
+var policy Policy
+
+// Init sets the hasher of new hashes.
+func Init(h Hasher) {
+	current = h
+}
+
+// InitPolicy sets the policy new passwords are checked against.
+func InitPolicy(p Policy) {
+	policy = p
+}
+
+// Check returns the problems of password under the configured policy.
+func Check(password string, personal ...string) []Problem {
+	return policy.Check(password, personal...)
+}
+
+// Hash hashes password with the configured hasher.
+func Hash(password string) (string, error) {
+	return current.Hash(password)
+}
+
+// Verify reports whether password matches hash, and whether hash should be
+// replaced by Hash(password) because the configured algorithm or its
+// parameters changed since it was made.
+func Verify(hash string, password string) (ok bool, rehash bool, err error) {
+	for _, algorithm := range algorithms {
+		if !algorithm.Owns(hash) {
+			continue
+		}
+
+		ok, err = algorithm.Verify(hash, password)
+		return ok, ok && !current.Current(hash), err
+	}
+
+	return false, false, ErrUnknownHash
+}
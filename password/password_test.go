@@ -0,0 +1,168 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// cheap parameters, the tests hash a lot
+var testArgon2id = Argon2id{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2id(t *testing.T) {
+	hash, err := testArgon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := regexp.MustCompile(`^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`)
+	if !format.MatchString(hash) {
+		t.Errorf("got hash %q, not in the PHC format", hash)
+	}
+
+	if ok, err := testArgon2id.Verify(hash, "correct horse"); !ok || err != nil {
+		t.Errorf("right password: got %t, %v", ok, err)
+	}
+	if ok, err := testArgon2id.Verify(hash, "correct horse "); ok || err != nil {
+		t.Errorf("wrong password: got %t, %v", ok, err)
+	}
+
+	again, _ := testArgon2id.Hash("correct horse")
+	if again == hash {
+		t.Errorf("two hashes of the same password are equal, the salt is not random")
+	}
+
+	if !testArgon2id.Owns(hash) || testArgon2id.Owns("$2a$04$abc") || testArgon2id.Owns("$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5") {
+		t.Errorf("Owns accepts the wrong hashes")
+	}
+
+	if !testArgon2id.Current(hash) {
+		t.Errorf("Current: the hash has the parameters of the hasher")
+	}
+	for _, other := range []Argon2id{{128, 1, 1}, {64, 2, 1}, {64, 1, 2}} {
+		if other.Current(hash) {
+			t.Errorf("Current of %+v: the hash has other parameters", other)
+		}
+	}
+}
+
+func TestParseArgon2id(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("correct horse"), salt, 2, 32, 1, 24)
+	b64 := base64.RawStdEncoding.EncodeToString
+
+	// a hash made elsewhere, with its own parameters and key length
+	hash := "$argon2id$v=19$m=32,t=2,p=1$" + b64(salt) + "$" + b64(key)
+	h, err := parseArgon2id(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.params != (Argon2id{Memory: 32, Iterations: 2, Parallelism: 1}) || string(h.salt) != string(salt) || len(h.key) != 24 {
+		t.Errorf("got %+v", h)
+	}
+	if ok, err := testArgon2id.Verify(hash, "correct horse"); !ok || err != nil {
+		t.Errorf("verify with the parameters of the hash: got %t, %v", ok, err)
+	}
+	if testArgon2id.Current(hash) || h.params.Current(hash) {
+		t.Errorf("Current: a 24 bytes key is not current")
+	}
+
+	malformed := []string{
+		"",
+		"argon2id",
+		"$argon2i$v=19$m=32,t=2,p=1$" + b64(salt) + "$" + b64(key),
+		"$argon2id$v=16$m=32,t=2,p=1$" + b64(salt) + "$" + b64(key),
+		"$argon2id$19$m=32,t=2,p=1$" + b64(salt) + "$" + b64(key),
+		"$argon2id$v=19$m=32,t=0,p=1$" + b64(salt) + "$" + b64(key),
+		"$argon2id$v=19$m=32,t=2,p=0$" + b64(salt) + "$" + b64(key),
+		"$argon2id$v=19$m=x,t=2,p=1$" + b64(salt) + "$" + b64(key),
+		"$argon2id$v=19$t=2,p=1$" + b64(salt) + "$" + b64(key),
+		"$argon2id$v=19$m=32,t=2,p=1$" + b64(salt) + "=$" + b64(key),
+		"$argon2id$v=19$m=32,t=2,p=1$" + b64(salt) + "$",
+		"$argon2id$v=19$m=32,t=2,p=1$" + b64(salt) + "$" + b64(key) + "$",
+		"$argon2id$v=19$m=32,t=2,p=1$" + b64(salt),
+	}
+	for _, hash := range malformed {
+		if _, err := parseArgon2id(hash); !errors.Is(err, ErrUnknownHash) {
+			t.Errorf("parse %q: got %v, want ErrUnknownHash", hash, err)
+		}
+		if ok, err := testArgon2id.Verify(hash, "correct horse"); ok || !errors.Is(err, ErrUnknownHash) {
+			t.Errorf("verify %q: got %t, %v", hash, ok, err)
+		}
+	}
+}
+
+func TestBcrypt(t *testing.T) {
+	b := Bcrypt{Cost: 4}
+
+	hash, err := b.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$2a$04$") {
+		t.Errorf("got hash %q", hash)
+	}
+
+	if ok, err := b.Verify(hash, "correct horse"); !ok || err != nil {
+		t.Errorf("right password: got %t, %v", ok, err)
+	}
+	if ok, err := b.Verify(hash, "correct horsE"); ok || err != nil {
+		t.Errorf("wrong password: got %t, %v", ok, err)
+	}
+	if ok, err := b.Verify("$2a$04$short", "correct horse"); ok || err == nil {
+		t.Errorf("malformed hash: got %t, %v", ok, err)
+	}
+
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if !b.Owns(prefix + strings.TrimPrefix(hash, "$2a$")) {
+			t.Errorf("Owns: %s hashes are bcrypt", prefix)
+		}
+	}
+	if b.Owns("$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5") || b.Owns("$2x$04$abc") {
+		t.Errorf("Owns accepts the wrong hashes")
+	}
+
+	if !b.Current(hash) || (Bcrypt{Cost: 5}).Current(hash) || b.Current("$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5") {
+		t.Errorf("Current: only the hashes of cost 4 are current")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	argon2Current, _ := testArgon2id.Hash("correct horse")
+	argon2Old, _ := Argon2id{Memory: 32, Iterations: 1, Parallelism: 1}.Hash("correct horse")
+	bcrypt4, _ := Bcrypt{Cost: 4}.Hash("correct horse")
+	bcrypt5, _ := Bcrypt{Cost: 5}.Hash("correct horse")
+
+	tests := []struct {
+		name     string
+		current  Hasher
+		hash     string
+		password string
+		ok       bool
+		rehash   bool
+		err      error
+	}{
+		{"current argon2id", testArgon2id, argon2Current, "correct horse", true, false, nil},
+		{"argon2id of old parameters", testArgon2id, argon2Old, "correct horse", true, true, nil},
+		{"bcrypt to argon2id", testArgon2id, bcrypt4, "correct horse", true, true, nil},
+		{"wrong password is never rehashed", testArgon2id, bcrypt4, "wrong horse", false, false, nil},
+		{"current bcrypt", Bcrypt{Cost: 4}, bcrypt4, "correct horse", true, false, nil},
+		{"bcrypt of another cost", Bcrypt{Cost: 4}, bcrypt5, "correct horse", true, true, nil},
+		{"argon2id to bcrypt", Bcrypt{Cost: 4}, argon2Current, "correct horse", true, true, nil},
+		{"unknown algorithm", testArgon2id, "$md5$abc", "correct horse", false, false, ErrUnknownHash},
+		{"plain text", testArgon2id, "correct horse", "correct horse", false, false, ErrUnknownHash},
+	}
+
+	for _, tt := range tests {
+		Init(tt.current)
+
+		ok, rehash, err := Verify(tt.hash, tt.password)
+		if ok != tt.ok || rehash != tt.rehash || !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %t, %t, %v, want %t, %t, %v", tt.name, ok, rehash, err, tt.ok, tt.rehash, tt.err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The problems a password can have, each is also the validation rule
+// reported to the client.
+const (
+	ProblemTooShort = "password_too_short"
+	ProblemTooLong  = "password_too_long"
+	ProblemWeak     = "password_weak"
+	ProblemCommon   = "password_common"
+	ProblemPersonal = "password_personal"
+)
+
+var Problems = []string{ProblemTooShort, ProblemTooLong, ProblemWeak, ProblemCommon, ProblemPersonal}
+
+// Problem is a policy rule a password breaks, Param is the limit of the
+// rule when it has one, e.g. the minimum length.
+type Problem struct {
+	Rule  string
+	Param string
+}
+
+// Policy is the strength a new password must have. Length counts
+// characters, MaxBytes also limits the encoded length for hashers with a
+// limit of their own, e.g. 72 bytes of bcrypt, 0 disables it.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	MaxBytes  int
+	// MinScore is the lowest accepted Score, from 0 to 4.
+	MinScore int
+}
+
+// Check returns every problem of password. Personal are values the password
+// must not contain, e.g. the email and the name of its user.
+func (p Policy) Check(password string, personal ...string) []Problem {
+	problems := []Problem{}
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		problems = append(problems, Problem{Rule: ProblemTooShort, Param: strconv.Itoa(p.MinLength)})
+	}
+
+	if length > p.MaxLength || p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		maxLength := p.MaxLength
+		if p.MaxBytes > 0 {
+			maxLength = min(maxLength, p.MaxBytes)
+		}
+		problems = append(problems, Problem{Rule: ProblemTooLong, Param: strconv.Itoa(maxLength)})
+	}
+
+	if isCommon(password) {
+		problems = append(problems, Problem{Rule: ProblemCommon})
+	} else if Score(password) < p.MinScore {
+		problems = append(problems, Problem{Rule: ProblemWeak})
+	}
+
+	if containsPersonal(password, personal) {
+		problems = append(problems, Problem{Rule: ProblemPersonal})
+	}
+
+	return problems
+}
+
+// Score rates password from 0 to 4 by its estimated entropy: the size of the
+// character classes it uses to the power of its length, where characters
+// repeating or continuing a sequence of the previous one, e.g. "aaa" or
+// "abc", add nothing.
+func Score(password string) int {
+	var lower, upper, digit, symbol, other bool
+	effective := 0
+	previous := rune(-1)
+
+	for _, r := range password {
+		switch {
+		case r < utf8.RuneSelf && unicode.IsLower(r):
+			lower = true
+		case r < utf8.RuneSelf && unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+
+		if r != previous && r != previous+1 && r != previous-1 {
+			effective++
+		}
+		previous = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	bits := float64(effective) * math.Log2(float64(pool))
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 80:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func containsPersonal(password string, personal []string) bool {
+	password = strings.ToLower(password)
+
+	for _, value := range personal {
+		value = strings.ToLower(value)
+
+		// the email itself and its local part, the name and each of its words
+		parts := strings.Fields(value)
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			parts = append(parts, local)
+		}
+
+		for _, part := range parts {
+			// shorter parts are too likely to appear by chance
+			if utf8.RuneCountInString(part) >= 3 && strings.Contains(password, part) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// common_passwords.txt.gz holds the most common leaked passwords, lowercased,
+// from the frequency lists of zxcvbn (MIT licensed).
+//
+//go:embed common_passwords.txt.gz
+var commonPasswordsGz []byte
+
+var commonPasswords struct {
+	once sync.Once
+	set  map[string]bool
+}
+
+func isCommon(password string) bool {
+	commonPasswords.once.Do(func() {
+		commonPasswords.set = map[string]bool{}
+
+		r, err := gzip.NewReader(bytes.NewReader(commonPasswordsGz))
+		if err != nil {
+			panic(err)
+		}
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			commonPasswords.set[scanner.Text()] = true
+		}
+
+		if err := scanner.Err(); err != nil {
+			panic(err)
+		}
+	})
+
+	return commonPasswords.set[strings.ToLower(password)]
+}
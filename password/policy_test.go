@@ -0,0 +1,96 @@
+package password
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxLength: 20, MinScore: 2}
+	bcryptPolicy := Policy{MinLength: 8, MaxLength: 64, MaxBytes: 72, MinScore: 2}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		personal []string
+		want     []Problem
+	}{
+		{"strong enough", policy, "zmqvxkwr", nil, []Problem{}},
+		{"short and weak", policy, "zmqvx", nil, []Problem{{ProblemTooShort, "8"}, {ProblemWeak, ""}}},
+		{"repeated", policy, "mmmmmmmmmm", nil, []Problem{{ProblemWeak, ""}}},
+		{"sequence", policy, "abcdefghijkl", nil, []Problem{{ProblemWeak, ""}}},
+		{"too long", policy, strings.Repeat("zmqvxkwr", 3), nil, []Problem{{ProblemTooLong, "20"}}},
+		{"characters are counted, not bytes", policy, "zmqvxkwr" + strings.Repeat("é", 12), nil, []Problem{}},
+		{"too many bytes", bcryptPolicy, "zmqvxkwr" + strings.Repeat("é", 33), nil, []Problem{{ProblemTooLong, "64"}}},
+		{"common", policy, "password", nil, []Problem{{ProblemCommon, ""}}},
+		{"common in another case", policy, "PassW0rd", nil, []Problem{{ProblemCommon, ""}}},
+		{"common and short", policy, "dragon", nil, []Problem{{ProblemTooShort, "8"}, {ProblemCommon, ""}}},
+		{"local part of the email", policy, "zmqvx-JDoe", []string{"jdoe@example.com", "Jane Doe"}, []Problem{{ProblemPersonal, ""}}},
+		{"whole email", policy, "jdoe@example.com!", []string{"jdoe@example.com"}, []Problem{{ProblemPersonal, ""}}},
+		{"word of the name", policy, "zmqvxjane", []string{"jdoe@example.com", "Jane Doe"}, []Problem{{ProblemPersonal, ""}}},
+		{"domain of the email", policy, "examplezmqvx", []string{"jdoe@example.com"}, []Problem{}},
+		{"short words of the name", policy, "zmqvxkwrjoli", []string{"Jo Li"}, []Problem{}},
+		{"no personal values", policy, "zmqvxjane", []string{"", " "}, []Problem{}},
+		{"everything", policy, "jdoe", []string{"jdoe@example.com"}, []Problem{{ProblemTooShort, "8"}, {ProblemWeak, ""}, {ProblemPersonal, ""}}},
+	}
+
+	for _, tt := range tests {
+		got := tt.policy.Check(tt.password, tt.personal...)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Check(%q) got %v, want %v", tt.name, tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		password string
+		score    int
+	}{
+		{"", 0},
+		{"zzzzzzzzzzzzzzzzzzzz", 0},
+		{"abcdefghijklmnopqrst", 0},
+		{"9876543210", 0},
+		{"zmqvxk", 1},
+		{"zmqvxkwr", 2},
+		{"zmqvxxxxkkkkwr", 2},
+		{"Zm9!qV#x", 2},
+		{"Zm9!qV#x4Kp&", 3},
+		{"Zm9!qV#x4Kp&Ty7@", 4},
+		{"ééééé", 0},
+		{"日本語の猫と犬", 2},
+	}
+
+	for _, tt := range tests {
+		if got := Score(tt.password); got != tt.score {
+			t.Errorf("Score(%q) = %d, want %d", tt.password, got, tt.score)
+		}
+	}
+}
+
+func TestCommonPasswords(t *testing.T) {
+	for _, password := range []string{"password", "123456", "qwerty", "trustno1", "iloveyou", "Dragon", "PASSWORD1"} {
+		if !isCommon(password) {
+			t.Errorf("%q is common", password)
+		}
+	}
+
+	for _, password := range []string{"", "zmqvxkwr", "correct horse battery"} {
+		if isCommon(password) {
+			t.Errorf("%q is not common", password)
+		}
+	}
+
+	// the embedded list is read whole and lowercased, as isCommon expects
+	if len(commonPasswords.set) < 5000 {
+		t.Errorf("got %d common passwords, the list was cut short", len(commonPasswords.set))
+	}
+	for password := range commonPasswords.set {
+		if password != strings.ToLower(password) || password == "" {
+			t.Errorf("common password %q is not lowercased", password)
+			break
+		}
+	}
+}